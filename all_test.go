@@ -6,10 +6,20 @@ package ini
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 const (
@@ -379,3 +389,1176 @@ func TestMerge(t *testing.T) {
 		t.Errorf("Expected '[X] x.four' to be 'x4' but instead it was '%s'", result)
 	}
 }
+
+// TestLoadConfigFiles tests the goconfig compatible API.
+func TestLoadConfigFiles(t *testing.T) {
+	c, err := LoadConfigFile("testdata/conf.ini", "testdata/conf2.ini")
+	tAssertNil(t, err)
+
+	tAssertEqual(t, "conf.ini does not have this key", c.MustValue("new section", "key1"))
+	tAssertEqual(t, "rewrite this key of conf.ini", c.MustValue("Demo", "key2"))
+	tAssertEqual(t, "www.google.fake", c.MustValue("url", "google_fake"))
+	tAssertTrue(t, c.HasSection("parent.child.child"))
+	v, err := c.GetValue("Demo", "key:2")
+	tAssertNil(t, err)
+	tAssertEqual(t, "this is based on \"key:1\" => `This is the value of \"key:1\"`", v)
+	tAssertEqual(t, "hello China!", c.MustValue("Demo", "chinese-var"))
+
+	tAssertFalse(t, c.SetValue("Demo", "key2", "hello man!"))
+	tAssertTrue(t, c.SetValue("Demo", "key4", "hello girl!"))
+	tAssertEqual(t, "hello man!", c.MustValue("Demo", "key2"))
+
+	// Reload drops the changes
+	tAssertNil(t, c.Reload())
+	tAssertEqual(t, "rewrite this key of conf.ini", c.MustValue("Demo", "key2"))
+	tAssertFalse(t, c.HasSectionKey("Demo", "key4"))
+	tAssertEqual(t, "conf.ini does not have this key", c.MustValue("new section", "key1"))
+
+	// Reload data needs a single file
+	tAssertNotNil(t, c.ReloadData(strings.NewReader("")))
+
+	_, err = LoadConfigFile("testdata/conf404.ini")
+	tAssertNotNil(t, err)
+}
+
+func TestAppendFilesAndReloadData(t *testing.T) {
+	c, err := LoadFromData([]byte("[Demo]\nkey2 = data\n"))
+	tAssertNil(t, err)
+	tAssertNotNil(t, c.Reload())
+
+	tAssertNil(t, c.ReloadData(strings.NewReader("[Demo]\nkey1 = reader\n")))
+	tAssertEqual(t, "reader", c.MustValue("Demo", "key1"))
+	tAssertFalse(t, c.HasSectionKey("Demo", "key2"))
+
+	c, err = LoadFromReader(strings.NewReader(""))
+	tAssertNil(t, err)
+	tAssertNil(t, c.AppendFiles("testdata/conf2.ini"))
+	tAssertEqual(t, "rewrite this key of conf.ini", c.MustValue("Demo", "key2"))
+	tAssertNotNil(t, c.AppendFiles("testdata/conf404.ini"))
+}
+
+func TestGoconfigMust(t *testing.T) {
+	c, err := LoadConfigFile("testdata/conf.ini")
+	tAssertNil(t, err)
+
+	v, err := c.Int64("parent", "age")
+	tAssertNil(t, err)
+	tAssertEqual(t, int64(32), v)
+	_, err = c.Int64("parent", "children")
+	tAssertNotNil(t, err)
+
+	tAssertEqual(t, int64(32), c.MustInt64("parent", "age"))
+	tAssertEqual(t, int64(3), c.MustInt64("parent", "children", 3))
+
+	val, ok := c.MustValueSet("parent.child", "died")
+	tAssertEqual(t, "", val)
+	tAssertFalse(t, ok)
+	val, ok = c.MustValueSet("parent.child", "died", "no")
+	tAssertEqual(t, "no", val)
+	tAssertTrue(t, ok)
+	val, ok = c.MustValueSet("What's this?", "empty_value", "no")
+	tAssertEqual(t, "no", val)
+	tAssertTrue(t, ok)
+
+	tAssertEqual(t, "joe", c.MustValueRange("What's this?", "name", "joe", []string{"hello"}))
+	tAssertEqual(t, "joe", c.MustValueRange("What's this?", "name404", "joe", []string{"hello"}))
+	tAssertEqual(t, "try one more value ^-^",
+		c.MustValueRange("What's this?", "name", "joe", []string{"hello", "try one more value ^-^"}))
+
+	tAssertEqual(t, []string{"1", "2", "3", "4", "5"}, c.MustValueArray("Demo", "array_key", ","))
+	tAssertEqual(t, []string{}, c.MustValueArray("Demo", "array_key404", ","))
+}
+
+// TestRoundTrip tests that a configuration is written back as it was read.
+func TestRoundTrip(t *testing.T) {
+	for _, fname := range []string{
+		"testdata/conf.ini",
+		"testdata/conf2.ini",
+		"testdata/conf_test.ini",
+		"testdata/source.ini",
+		"testdata/target.ini",
+	} {
+		data, err := os.ReadFile(fname)
+		tAssertNil(t, err, fname)
+
+		c, err := Load(fname, nil)
+		tAssertNil(t, err, fname)
+
+		var b bytes.Buffer
+		tAssertNil(t, c.WriteTo(&b, ""), fname)
+		tAssertEqual(t, string(data), b.String(), fname)
+	}
+
+	for _, data := range []string{
+		"",
+		"\n",
+		"a=1",
+		"a = 1\r\n; comment\r\n\r\n[s]\r\nb : 2 ; comment\r\n",
+		"# top\n\n[s] # header\n  # indented comment\nk=v\n line2\n\n\tline3\n\n; tail\n",
+		"[DEFAULT]\nd=1\n[s]\nk=1\nk=2\n",
+		"\xEF\xBB\xBFk=v\n",
+		"[s]\na=1\n[t]\nx=1\n[s]\nb=2\n",
+		"[s]\na=1\n[s]\n; b\nb=2\n",
+		"[s]\na=1\n[t]\n; empty\n[s]\n\n[t]\ny=2\n[s]\nb=2\n[t]\nz=3\n[s]\n",
+		"a=1\n[s]\nx=1\n[DEFAULT]\nb=2\n",
+		"[s]\nx=1\n[DEFAULT]\nb=2\n",
+		"[server]\nport = 80\nhost = a\n; override\nport = 8080\n",
+		"[s]\nk=1\nk=2\n; c\nk=3\n",
+		"[s]\na=1\nk=1\nb=2\nb=3\nk=2\n",
+		"[s]\nk=1\n[t]\nx=1\n[s]\nk=2\n",
+		"[s]\na=1\n[t]\n[s]\nk=1\n[t]\ny=2\n[s]\nk=2\nb=3\n",
+		"[s]\na=1\n[s]\nk=1\nb=2\n[s]\nk=2\n",
+	} {
+		c, err := LoadFrom(strings.NewReader(data), nil)
+		tAssertNil(t, err, data)
+
+		var b bytes.Buffer
+		tAssertNil(t, c.WriteTo(&b, ""), data)
+		tAssertEqual(t, data, b.String())
+	}
+}
+
+// TestRoundTripEdit tests that editing an option only rewrites its line.
+func TestRoundTripEdit(t *testing.T) {
+	const data = "; Google\n" +
+		"google = www.google.com ; search\n" +
+		"\n" +
+		"[Demo]\n" +
+		"key1:   value1\n" +
+		"\"key:1\"= value of key:1\n" +
+		"multi = line1\n" +
+		"  line2\n" +
+		"last=x"
+
+	c, err := LoadFrom(strings.NewReader(data), nil)
+	tAssertNil(t, err)
+	tAssertEqual(t, "value of key:1", c.MustValue("Demo", "key:1"))
+
+	c.AddSectionKey("", "google", "www.google.cn")
+	c.AddSectionKey("Demo", "key1", "value2")
+	c.AddSectionKey("Demo", "key:1", "new")
+	c.AddSectionKey("Demo", "multi", "a\nb")
+	c.AddSectionKey("Demo", "key=2", "v")
+	c.AddSectionKey("New", "k", "v")
+
+	var b bytes.Buffer
+	tAssertNil(t, c.WriteTo(&b, ""))
+	tAssertEqual(t, "; Google\n"+
+		"google = www.google.cn ; search\n"+
+		"\n"+
+		"[Demo]\n"+
+		"key1:   value2\n"+
+		"\"key:1\"= new\n"+
+		"multi = a\n"+
+		"\tb\n"+
+		"last=x\n"+
+		"\"key=2\" = v\n"+
+		"\n"+
+		"[New]\n"+
+		"k = v", b.String())
+
+	c, err = LoadFrom(&b, nil)
+	tAssertNil(t, err)
+	tAssertEqual(t, "a\nb", c.MustValue("Demo", "multi"))
+	tAssertEqual(t, "v", c.MustValue("Demo", "key=2"))
+	tAssertEqual(t, "v", c.MustValue("New", "k"))
+
+	// repeated section headers
+	c, err = LoadFrom(strings.NewReader("[s]\na=1\n[t]\nx=1\n[s]\nb=2\nc=3\nempty =\n[t]\n; y\ny=2\n"), nil)
+	tAssertNil(t, err)
+	tAssertTrue(t, c.RemoveSectionKey("s", "b"))
+	tAssertTrue(t, c.RemoveSectionKey("t", "y"))
+	c.AddSectionKey("s", "d", "4")
+	c.AddSectionKey("s", "empty", "x")
+
+	b.Reset()
+	tAssertNil(t, c.WriteTo(&b, ""))
+	tAssertEqual(t, "[s]\na=1\n[t]\nx=1\n[s]\nc=3\nempty = x\nd = 4\n", b.String())
+
+	// """quoted""" keys and values
+	c, err = LoadFrom(strings.NewReader("\"\"\"a`b\"c\"\"\" = \"\"\"x \"y\"\"\"\" ; z\n"), nil)
+	tAssertNil(t, err)
+	tAssertEqual(t, `x "y"`, c.MustValue(DEFAULT_SECTION, "a`b\"c"))
+	c.AddSectionKey("", "a`b\"c", "`w`")
+	c.AddSectionKey("", "d:`e\"f", "v")
+	b.Reset()
+	tAssertNil(t, c.WriteTo(&b, ""))
+	tAssertEqual(t, "\"\"\"a`b\"c\"\"\" = \"\"\"`w`\"\"\" ; z\n\"\"\"d:`e\"f\"\"\" = v\n", b.String())
+
+	c, err = LoadFrom(&b, nil)
+	tAssertNil(t, err)
+	tAssertEqual(t, "`w`", c.MustValue(DEFAULT_SECTION, "a`b\"c"))
+	tAssertEqual(t, "v", c.MustValue(DEFAULT_SECTION, "d:`e\"f"))
+
+	// options named ""
+	c = New(nil)
+	c.AddSectionKey("s", "", "v")
+	c.AddSectionKey("s", "k", "w")
+	b.Reset()
+	tAssertNil(t, c.WriteTo(&b, ""))
+	tAssertEqual(t, "\r\n[s]\r\n\"\" = v\r\nk = w\r\n\r\n", b.String())
+
+	const empty = "[s]\n\"\"=v\nk=w\n"
+	c, err = LoadFrom(strings.NewReader(empty), nil)
+	tAssertNil(t, err)
+	tAssertEqual(t, []string{`""`, "k"}, c.GetSectionKeyList("s"))
+	b.Reset()
+	tAssertNil(t, c.WriteTo(&b, ""))
+	tAssertEqual(t, empty, b.String())
+}
+
+// TestParseError tests the position and reason of parse errors.
+func TestParseError(t *testing.T) {
+	const data = "k=v\n" +
+		"[s]\n" +
+		"bad line ; comment\n" +
+		"  = no name\n" +
+		"[unclosed\n" +
+		"ok:1\n"
+
+	_, err := LoadFrom(strings.NewReader(data), nil)
+	var pe *ParseError
+	tAssertTrue(t, errors.As(err, &pe))
+	tAssertEqual(t, &ParseError{
+		Line:   3,
+		Column: 1,
+		Text:   "bad line ; comment",
+		Reason: "missing separator",
+	}, pe)
+	tAssertEqual(t, `ini: <input>:3:1: could not parse line: missing separator: "bad line ; comment"`, err.Error())
+
+	_, err = LoadFrom(strings.NewReader(data), &Options{AllErrors: true})
+	errs, ok := err.(ParseErrors)
+	tAssertTrue(t, ok)
+	tAssertEqual(t, 3, len(errs))
+	tAssertEqual(t, 4, errs[1].Line)
+	tAssertEqual(t, 3, errs[1].Column)
+	tAssertEqual(t, "continuation line without option", errs[1].Reason)
+	tAssertEqual(t, 5, errs[2].Line)
+	tAssertEqual(t, 10, errs[2].Column)
+	tAssertTrue(t, errors.As(err, &pe))
+	tAssertEqual(t, 3, pe.Line)
+
+	// without the multiple error Unwrap of Go 1.20
+	pe = nil
+	tAssertTrue(t, errs.As(&pe))
+	tAssertEqual(t, errs[0], pe)
+	tAssertFalse(t, errs.Is(ErrIncludeCycle))
+	tAssertTrue(t, append(errs, &ParseError{Err: ErrIncludeCycle}).Is(ErrIncludeCycle))
+
+	_, err = LoadFrom(strings.NewReader("[s]\n=v\n"), nil)
+	tAssertTrue(t, errors.As(err, &pe))
+	tAssertEqual(t, "missing option name", pe.Reason)
+
+	if err := os.WriteFile(tmpFilename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpFilename)
+
+	_, err = Load(tmpFilename, nil)
+	tAssertTrue(t, errors.As(err, &pe))
+	tAssertEqual(t, tmpFilename, pe.Source)
+}
+
+// TestLookupErrors tests the errors of the getters.
+func TestLookupErrors(t *testing.T) {
+	c := New(nil)
+	c.AddSectionKey("", "host", "example.com")
+	c.AddSectionKey("s", "url", "http://%(host)s/%(path)s")
+	c.AddSectionKey("s", "empty", "")
+	c.AddSectionKey("s", "ref-empty", "[%(empty)s]")
+	c.AddSectionKey("s", "env", "${GO_CONFIGFILE_TEST_UNSET_ENV_VAR}")
+	c.AddSectionKey("s", "a", "%(b)s")
+	c.AddSectionKey("s", "b", "%(a)s")
+
+	var ke *KeyError
+	_, err := c.GetValue("s", "none")
+	tAssertTrue(t, errors.Is(err, ErrKeyNotFound))
+	tAssertTrue(t, errors.As(err, &ke))
+	tAssertEqual(t, &KeyError{Section: "s", Key: "none", Err: ErrKeyNotFound}, ke)
+
+	_, err = c.GetValue("none", "none")
+	tAssertTrue(t, errors.Is(err, ErrSectionNotFound))
+	_, err = c.GetInt("none", "none")
+	tAssertTrue(t, errors.Is(err, ErrSectionNotFound))
+	_, err = c.GetDefaultValue("none")
+	tAssertTrue(t, errors.Is(err, ErrKeyNotFound))
+
+	v, err := c.GetString("none", "host")
+	tAssertNil(t, err)
+	tAssertEqual(t, "example.com", v)
+	v, err = c.GetString("s", "ref-empty")
+	tAssertNil(t, err)
+	tAssertEqual(t, "[]", v)
+
+	var ie *InterpolationError
+	_, err = c.GetString("s", "url")
+	tAssertTrue(t, errors.Is(err, ErrUnresolvedReference))
+	tAssertTrue(t, errors.As(err, &ie))
+	tAssertEqual(t, []string{"%(path)s"}, ie.Chain)
+	tAssertEqual(t, "url", ie.Key)
+
+	_, err = c.GetString("s", "env")
+	tAssertTrue(t, errors.Is(err, ErrUnresolvedReference))
+
+	_, err = c.GetBool("s", "a")
+	tAssertTrue(t, errors.Is(err, ErrInterpolationCycle))
+	tAssertTrue(t, errors.As(err, &ie))
+	tAssertEqual(t, "%(b)s", ie.Chain[0])
+	tAssertEqual(t, "%(a)s", ie.Chain[1])
+
+	defer func() {
+		err, _ := recover().(error)
+		tAssertTrue(t, errors.Is(err, ErrKeyNotFound))
+	}()
+	c.MustString("s", "none")
+}
+
+// TestBlockMode tests concurrent use of a Config, run with -race.
+func TestBlockMode(t *testing.T) {
+	c, err := LoadConfigFile("testdata/conf.ini")
+	tAssertNil(t, err)
+	tAssertTrue(t, c.BlockMode)
+	c, err = LoadFromData([]byte("a = 1\n"))
+	tAssertNil(t, err)
+	tAssertTrue(t, c.BlockMode)
+	c, err = Load("testdata/conf.ini", nil)
+	tAssertNil(t, err)
+	tAssertFalse(t, c.BlockMode)
+
+	c, err = Load("testdata/conf.ini", &Options{BlockMode: true})
+	tAssertNil(t, err)
+	tAssertTrue(t, c.BlockMode)
+
+	source, err := Load(sourceFilename, &Options{BlockMode: true})
+	tAssertNil(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			section := fmt.Sprintf("section%d", i)
+			for j := 0; j < 100; j++ {
+				c.AddSectionKey(section, "key", fmt.Sprint(j))
+				c.AddSectionKey("Demo", "key2", fmt.Sprint(j))
+				c.RemoveSectionKey(section, "key")
+				c.RemoveSection(section)
+				c.MergeFrom(source)
+			}
+		}(i)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.GetString("url", "google_url")
+				c.GetInt("parent", "age")
+				c.HasSectionKey("Demo", "key2")
+				c.GetSectionKeyList("Demo")
+				c.GetSectionList()
+				c.WriteTo(io.Discard, "")
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 10; j++ {
+			if err := c.Reload(); err != nil {
+				t.Error(err)
+			}
+			var parent tPerson
+			if err := c.Section("parent").MapTo(&parent); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	wg.Wait()
+
+	tAssertEqual(t, 32, c.MustInt("parent", "age"))
+
+	// merging two configurations into each other does not deadlock
+	for j := 0; j < 100; j++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c.MergeFrom(source)
+			c.ApplyDefaults(source)
+		}()
+		go func() {
+			defer wg.Done()
+			source.MergeFrom(c)
+			source.ApplyDefaults(c)
+		}()
+	}
+	wg.Wait()
+}
+
+// TestOrder tests the order of sections and options after removals.
+func TestOrder(t *testing.T) {
+	c := New(nil)
+	for _, section := range []string{"a", "b", "c", "d"} {
+		for _, option := range []string{"1", "2", "3", "4"} {
+			c.AddSectionKey(section, option, section+option)
+		}
+	}
+
+	tAssertTrue(t, c.RemoveSection("b"))
+	tAssertTrue(t, c.RemoveSectionKey("c", "2"))
+	tAssertTrue(t, c.RemoveSectionKey("c", "1"))
+	tAssertTrue(t, c.AddSection("b"))
+	tAssertTrue(t, c.AddSectionKey("c", "1", "c1"))
+	tAssertEqual(t, []string{DEFAULT_SECTION, "a", "c", "d", "b"}, c.GetSectionList())
+	tAssertEqual(t, []string{"3", "4", "1"}, c.GetSectionKeyList("c"))
+	tAssertEqual(t, []string(nil), c.GetSectionKeyList("b"))
+
+	target := New(nil)
+	target.AddSectionKey("d", "9", "d9")
+	target.MergeFrom(c)
+	tAssertEqual(t, []string{DEFAULT_SECTION, "d", "a", "c", "b"}, target.GetSectionList())
+	tAssertEqual(t, []string{"9", "1", "2", "3", "4"}, target.GetSectionKeyList("d"))
+}
+
+// tLargeConfig returns a configuration with n sections of n options.
+func tLargeConfig(n int) *Config {
+	c := New(nil)
+	for i := 0; i < n; i++ {
+		section := fmt.Sprintf("section%d", i)
+		for j := 0; j < n; j++ {
+			c.AddSectionKey(section, fmt.Sprintf("option%d", j), fmt.Sprint(j))
+		}
+	}
+	return c
+}
+
+func Benchmark_GetSectionList(b *testing.B) {
+	c := tLargeConfig(300)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.GetSectionList()
+	}
+}
+
+func Benchmark_GetSectionKeyList(b *testing.B) {
+	c := tLargeConfig(300)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.GetSectionKeyList("section150")
+	}
+}
+
+func Benchmark_WriteTo(b *testing.B) {
+	c := tLargeConfig(300)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.WriteTo(io.Discard, "")
+	}
+}
+
+func Benchmark_MergeFrom(b *testing.B) {
+	source := tLargeConfig(300)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		New(nil).MergeFrom(source)
+	}
+}
+
+// TestMoveKey tests inserting and moving options and sections.
+func TestMoveKey(t *testing.T) {
+	const data = "top = 1\n" +
+		"[a]\n" +
+		"# one\n" +
+		"1 = a1\n" +
+		"2 = a2\n" +
+		"3 = a3\n" +
+		"\n" +
+		"[b]\n" +
+		"1 = b1\n"
+
+	c, err := LoadFrom(strings.NewReader(data), nil)
+	tAssertNil(t, err)
+
+	tAssertTrue(t, c.InsertKeyBefore("a", "0", "a0", "1"))
+	tAssertTrue(t, c.InsertKeyAfter("a", "4", "a4", "3"))
+	tAssertTrue(t, c.InsertKeyAfter("a", "2", "a2-new", "4"))
+	tAssertFalse(t, c.InsertKeyAfter("a", "5", "a5", "404"))
+	tAssertFalse(t, c.HasSectionKey("a", "5"))
+	tAssertEqual(t, []string{"0", "1", "3", "4", "2"}, c.GetSectionKeyList("a"))
+
+	tAssertTrue(t, c.MoveKey("a", "1", "4", true))
+	tAssertTrue(t, c.MoveKey("a", "0", "0", true))
+	tAssertFalse(t, c.MoveKey("a", "404", "0", true))
+	tAssertFalse(t, c.MoveKey("a", "0", "404", true))
+	tAssertEqual(t, []string{"0", "3", "4", "1", "2"}, c.GetSectionKeyList("a"))
+
+	// overwrite keeps the position
+	tAssertFalse(t, c.AddSectionKey("a", "3", "a3-new"))
+	tAssertEqual(t, []string{"0", "3", "4", "1", "2"}, c.GetSectionKeyList("a"))
+
+	tAssertTrue(t, c.RemoveSectionKey("a", "4"))
+	tAssertTrue(t, c.AddSectionKey("a", "4", "a4"))
+	tAssertEqual(t, []string{"0", "3", "1", "2", "4"}, c.GetSectionKeyList("a"))
+
+	tAssertTrue(t, c.MoveSection("b", "a", false))
+	tAssertTrue(t, c.MoveSection("", "b", true))
+	tAssertFalse(t, c.MoveSection("404", "b", true))
+	tAssertEqual(t, []string{"b", DEFAULT_SECTION, "a"}, c.GetSectionList())
+
+	var b bytes.Buffer
+	tAssertNil(t, c.WriteTo(&b, ""))
+	tAssertEqual(t, "\n"+
+		"[b]\n"+
+		"1 = b1\n"+
+		"\n"+
+		"[DEFAULT]\n"+
+		"top = 1\n"+
+		"[a]\n"+
+		"0 = a0\n"+
+		"3 = a3-new\n"+
+		"# one\n"+
+		"1 = a1\n"+
+		"2 = a2-new\n"+
+		"4 = a4\n", b.String())
+}
+
+// TestSectionRefs tests references to options of other sections.
+func TestSectionRefs(t *testing.T) {
+	const data = "name = default\n" +
+		"[paths]\n" +
+		"root = /srv\n" +
+		"data = %(root)s/data\n" +
+		"name = paths\n" +
+		"[parent.child]\n" +
+		"age = 3\n" +
+		"[server]\n" +
+		"x.one = dotted\n" +
+		"data = %(paths.data)s\n" +
+		"log = ${paths:root}/log\n" +
+		"age = %(parent.child.age)s\n" +
+		"dotted = %(x.one)s\n" +
+		"names = %(DEFAULT.name)s/%(paths.name)s/%(name)s\n" +
+		"missing = %(paths.404)s\n" +
+		"missing2 = ${paths:404}\n" +
+		"literal = ${not a reference}\n" +
+		"a = %(b)s\n" +
+		"b = %(c)s\n" +
+		"c = %(server.a)s\n"
+
+	c, err := LoadFrom(strings.NewReader(data), nil)
+	tAssertNil(t, err)
+
+	testGet(t, c, "server", "data", "/srv/data")
+	testGet(t, c, "server", "log", "/srv/log")
+	testGet(t, c, "server", "age", "3")
+	testGet(t, c, "server", "dotted", "dotted")
+	testGet(t, c, "server", "names", "default/paths/default")
+	testGet(t, c, "server", "literal", "${not a reference}")
+
+	_, err = c.GetString("server", "missing")
+	tAssertTrue(t, errors.Is(err, ErrUnresolvedReference))
+	_, err = c.GetString("server", "missing2")
+	tAssertTrue(t, errors.Is(err, ErrUnresolvedReference))
+
+	var ie *InterpolationError
+	_, err = c.GetString("server", "a")
+	tAssertTrue(t, errors.Is(err, ErrInterpolationCycle))
+	tAssertTrue(t, errors.As(err, &ie))
+	tAssertEqual(t, []string{"%(b)s", "%(c)s", "%(server.a)s"}, ie.Chain)
+	tAssertEqual(t, "ini: cycle while unfolding option 'a' in section 'server': %(b)s -> %(c)s -> %(server.a)s", err.Error())
+
+	c, err = LoadFrom(strings.NewReader(data), &Options{NoSectionRefs: true})
+	tAssertNil(t, err)
+	testGet(t, c, "server", "dotted", "dotted")
+	_, err = c.GetString("server", "data")
+	tAssertTrue(t, errors.Is(err, ErrUnresolvedReference))
+	testGet(t, c, "server", "log", "${paths:root}/log")
+
+	c, err = LoadFrom(strings.NewReader("[paths]\nroot = /srv\n[s]\ndata = %(paths::root)s\nlog = %(paths.root)s\n"),
+		&Options{RefSeparator: "::"})
+	tAssertNil(t, err)
+	testGet(t, c, "s", "data", "/srv")
+	_, err = c.GetString("s", "log")
+	tAssertTrue(t, errors.Is(err, ErrUnresolvedReference))
+}
+
+func TestEnvModifiers(t *testing.T) {
+	env := map[string]string{"PORT": "9090", "EMPTY": "", "HOST": "example.com"}
+	lookupEnv := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+
+	data := `[server]
+port = ${PORT:-8080}
+port2 = ${UNSET:-8080}
+empty = ${EMPTY:-fallback}
+empty2 = ${EMPTY-fallback}
+assign = ${UNSET:=80}
+alt = ${HOST:+https://${HOST}}
+alt2 = ${UNSET:+set}
+nested = ${UNSET:-${HOST}:${PORT}}
+word = ${UNSET:-%(port)s}
+dollar = $$HOME \${HOME}
+required = ${UNSET:?must be set}
+required2 = ${EMPTY:?}
+`
+	c, err := LoadFrom(strings.NewReader(data), &Options{LookupEnv: lookupEnv})
+	tAssertNil(t, err)
+
+	testGet(t, c, "server", "port", "9090")
+	testGet(t, c, "server", "port2", "8080")
+	testGet(t, c, "server", "empty", "fallback")
+	testGet(t, c, "server", "empty2", "")
+	testGet(t, c, "server", "assign", "80")
+	testGet(t, c, "server", "alt", "https://example.com")
+	testGet(t, c, "server", "alt2", "")
+	testGet(t, c, "server", "nested", "example.com:9090")
+	testGet(t, c, "server", "word", "9090")
+	testGet(t, c, "server", "dollar", "$HOME ${HOME}")
+
+	var ie *InterpolationError
+	_, err = c.GetString("server", "required")
+	tAssertTrue(t, errors.Is(err, ErrUnresolvedReference))
+	tAssertTrue(t, errors.As(err, &ie))
+	tAssertEqual(t, []string{"${UNSET:?must be set}"}, ie.Chain)
+	tAssertEqual(t, "ini: UNSET: must be set while unfolding option 'required' in section 'server': ${UNSET:?must be set}", err.Error())
+	_, err = c.GetString("server", "required2")
+	tAssertTrue(t, errors.Is(err, ErrUnresolvedReference))
+}
+
+func TestResolvers(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "db_pass")
+	tAssertNil(t, os.WriteFile(secret, []byte("s3cret\n"), 0600))
+
+	data := `[DEFAULT]
+secrets = ` + filepath.Dir(secret) + `
+[db]
+pass = ${file:%(secrets)s/db_pass}
+missing = ${file:%(secrets)s/missing}
+home = ${env:HOME}
+token = ${vault:db/token}
+denied = ${vault:other}
+cmd = ${cmd:echo hello}
+[file]
+name = not a resolver
+`
+	opt := &Options{LookupEnv: func(key string) (string, bool) {
+		if key == "HOME" {
+			return "/home/ini", true
+		}
+		return "", false
+	}}
+
+	// file is not registered by default
+	c, err := LoadFrom(strings.NewReader(data), opt)
+	tAssertNil(t, err)
+	_, err = c.GetString("db", "pass")
+	tAssertTrue(t, errors.Is(err, ErrUnresolvedReference))
+	tAssertFalse(t, errors.Is(err, os.ErrNotExist))
+
+	opt.FileResolver = true
+	c, err = LoadFrom(strings.NewReader(data), opt)
+	tAssertNil(t, err)
+
+	c.RegisterResolver("vault", func(ref string) (string, error) {
+		if ref != "db/token" {
+			return "", errors.New("access denied")
+		}
+		return "t0ken", nil
+	})
+
+	testGet(t, c, "db", "pass", "s3cret")
+	testGet(t, c, "db", "home", "/home/ini")
+	testGet(t, c, "db", "token", "t0ken")
+
+	var re *ResolverError
+	_, err = c.GetString("db", "missing")
+	tAssertTrue(t, errors.Is(err, ErrUnresolvedReference))
+	tAssertTrue(t, errors.Is(err, os.ErrNotExist))
+	tAssertTrue(t, errors.As(err, &re))
+	tAssertEqual(t, "file", re.Scheme)
+	tAssertEqual(t, filepath.Join(filepath.Dir(secret), "missing"), re.Ref)
+
+	// without the multiple error Unwrap of Go 1.20
+	var pathErr *os.PathError
+	tAssertTrue(t, re.Is(ErrUnresolvedReference))
+	tAssertTrue(t, re.Is(os.ErrNotExist))
+	tAssertFalse(t, re.Is(ErrInterpolationCycle))
+	tAssertTrue(t, re.As(&pathErr))
+
+	_, err = c.GetString("db", "denied")
+	tAssertTrue(t, errors.Is(err, ErrUnresolvedReference))
+	tAssertEqual(t, "ini: vault resolver: access denied while unfolding option 'denied' in section 'db': ${vault:other}", err.Error())
+
+	// cmd is not registered by default
+	_, err = c.GetString("db", "cmd")
+	tAssertTrue(t, errors.Is(err, ErrUnresolvedReference))
+	if _, err := exec.LookPath("echo"); err == nil {
+		c.RegisterResolver("cmd", CommandResolver)
+		testGet(t, c, "db", "cmd", "hello")
+	}
+
+	c.RegisterResolver("vault", nil)
+	_, err = c.GetString("db", "token")
+	tAssertTrue(t, errors.Is(err, ErrUnresolvedReference))
+	tAssertFalse(t, errors.As(err, &re))
+}
+
+func TestEscapes(t *testing.T) {
+	data := `[s]
+name = ini
+var = %%(name)s
+env = $${HOME}
+percent = 100%%
+mixed = %(name)s: %%(name)s
+`
+	c, err := LoadFromData([]byte(data))
+	tAssertNil(t, err)
+	testGet(t, c, "s", "var", "%(name)s")
+	testGet(t, c, "s", "env", "${HOME}")
+	testGet(t, c, "s", "percent", "100%")
+	testGet(t, c, "s", "mixed", "ini: %(name)s")
+
+	v, err := c.GetRawString("s", "mixed")
+	tAssertNil(t, err)
+	tAssertEqual(t, "%(name)s: %%(name)s", v)
+	_, err = c.GetRawString("s", "missing")
+	tAssertTrue(t, errors.Is(err, ErrKeyNotFound))
+
+	c, err = LoadFrom(strings.NewReader(data), &Options{NoInterpolate: true})
+	tAssertNil(t, err)
+	testGet(t, c, "s", "mixed", "%(name)s: %%(name)s")
+	testGet(t, c, "s", "env", "$${HOME}")
+}
+
+func TestSubsections(t *testing.T) {
+	data := `[core]
+bare = false
+[remote "origin"]
+url = https://example.com/ini.git
+[branch "main"]
+remote = origin
+[remote  "with \"quotes\" and \\"]
+url = /tmp/quotes
+`
+	opt := &Options{Subsections: true}
+	c, err := LoadFrom(strings.NewReader(data), opt)
+	tAssertNil(t, err)
+
+	tAssertEqual(t, []string{"origin", `with "quotes" and \`}, c.GetSubsectionList("remote"))
+	tAssertEqual(t, []string{"main"}, c.GetSubsectionList("branch"))
+	tAssertEqual(t, []string(nil), c.GetSubsectionList("core"))
+	testGet(t, c, SubsectionName("remote", "origin"), "url", "https://example.com/ini.git")
+	testGet(t, c, `remote "with \"quotes\" and \\"`, "url", "/tmp/quotes")
+
+	section, sub, ok := SplitSubsection(`remote "with \"quotes\" and \\"`)
+	tAssertTrue(t, ok)
+	tAssertEqual(t, "remote", section)
+	tAssertEqual(t, `with "quotes" and \`, sub)
+	_, _, ok = SplitSubsection("core")
+	tAssertFalse(t, ok)
+
+	c.AddSectionKey(SubsectionName("remote", `a"b`), "url", "/tmp/ab")
+	var buf bytes.Buffer
+	tAssertNil(t, c.WriteTo(&buf, ""))
+	tAssertTrue(t, strings.HasSuffix(buf.String(), "\n[remote \"a\\\"b\"]\nurl=/tmp/ab\n"))
+
+	c, err = LoadFrom(&buf, opt)
+	tAssertNil(t, err)
+	tAssertEqual(t, []string{"origin", `with "quotes" and \`, `a"b`}, c.GetSubsectionList("remote"))
+
+	// Without the option the header is the section name
+	c, err = LoadFrom(strings.NewReader(data), nil)
+	tAssertNil(t, err)
+	testGet(t, c, `remote  "with \"quotes\" and \\"`, "url", "/tmp/quotes")
+
+	_, err = LoadFrom(strings.NewReader("[remote \"origin]\n"), opt)
+	var pe *ParseError
+	tAssertTrue(t, errors.As(err, &pe))
+	tAssertEqual(t, "invalid subsection", pe.Reason)
+	tAssertEqual(t, 9, pe.Column)
+}
+
+func TestSectionHierarchy(t *testing.T) {
+	c, err := LoadConfigFile("testdata/conf.ini")
+	tAssertNil(t, err)
+
+	tAssertEqual(t, []string{"parent.child"}, c.ChildSections("parent"))
+	tAssertEqual(t, []string{"parent.child.child"}, c.ChildSections("parent.child"))
+	tAssertEqual(t, []string(nil), c.ChildSections("parent.child.child"))
+	tAssertEqual(t, "parent.child", c.ParentSection("parent.child.child"))
+	tAssertEqual(t, "parent", c.ParentSection("parent.child"))
+	tAssertEqual(t, "", c.ParentSection("parent"))
+
+	c, err = Load("testdata/conf.ini", nil)
+	tAssertNil(t, err)
+	_, err = c.GetValue("parent.child.child", "relation")
+	tAssertTrue(t, errors.Is(err, ErrKeyNotFound))
+
+	c, err = Load("testdata/conf.ini", &Options{Inherit: true})
+	tAssertNil(t, err)
+	testGet(t, c, "parent.child", "age", "3")
+	testGet(t, c, "parent.child", "relation", "father")
+	testGet(t, c, "parent.child.child", "age", "3")
+	testGet(t, c, "parent.child.child", "relation", "father")
+	testGet(t, c, "parent.missing", "sex", "male")
+	_, err = c.GetValue("parent.child.child", "missing")
+	tAssertTrue(t, errors.Is(err, ErrKeyNotFound))
+
+	c, err = LoadFrom(strings.NewReader("[a]\nx = 1\n[a/b]\n[a/b.c]\n"),
+		&Options{Inherit: true, SectionSeparator: "/"})
+	tAssertNil(t, err)
+	tAssertEqual(t, []string{"a/b", "a/b.c"}, c.ChildSections("a"))
+	testGet(t, c, "a/b.c", "x", "1")
+
+	// the separator is not looked up in the subsections
+	c, err = LoadFrom(strings.NewReader("[remote]\nx = 1\n[remote \"a.b\"]\n[a \"x.y\"]\nz = 2\n[a.b \"x.y\"]\n"),
+		&Options{Inherit: true, Subsections: true})
+	tAssertNil(t, err)
+	tAssertEqual(t, "", c.ParentSection(`remote "a.b"`))
+	tAssertEqual(t, `a "x.y"`, c.ParentSection(`a.b "x.y"`))
+	tAssertEqual(t, []string{`a.b "x.y"`}, c.ChildSections(`a "x.y"`))
+	testGet(t, c, `a.b "x.y"`, "z", "2")
+	_, err = c.GetValue(`remote "a.b"`, "x")
+	tAssertTrue(t, errors.Is(err, ErrKeyNotFound))
+}
+
+func TestDuplicateKeys(t *testing.T) {
+	c, err := LoadConfigFile("testdata/conf.ini")
+	tAssertNil(t, err)
+	tAssertEqual(t, []string{"#1", "#2", "#3"}, c.GetSectionKeyList("auto increment"))
+	testGet(t, c, "auto increment", "#1", "hello")
+	testGet(t, c, "auto increment", "#3", "config")
+
+	tAssertTrue(t, c.AddSectionKey("auto increment", "-", "again"))
+	testGet(t, c, "auto increment", "#4", "again")
+	var buf bytes.Buffer
+	tAssertNil(t, c.WriteTo(&buf, ""))
+	tAssertTrue(t, strings.Contains(buf.String(), "- = config\n- = again\n"))
+
+	// only the "-" options are written as "-"
+	c = New(nil)
+	c.AddSectionKey("s", "#1", "one")
+	c.AddSectionKey("s", "#5", "five")
+	c.AddSectionKey("s", "-", "auto")
+	tAssertEqual(t, []string{"#1", "#5", "#2"}, c.GetSectionKeyList("s"))
+	buf.Reset()
+	tAssertNil(t, c.WriteTo(&buf, ""))
+	c, err = LoadFrom(&buf, nil)
+	tAssertNil(t, err)
+	tAssertEqual(t, []string{"#1", "#5", "#2"}, c.GetSectionKeyList("s"))
+	testGet(t, c, "s", "#5", "five")
+	testGet(t, c, "s", "#2", "auto")
+
+	data := "[s]\nk = 1\nk = 2\n\tcontinued\nother = x\nk = 3\n"
+
+	c, err = LoadFrom(strings.NewReader(data), nil)
+	tAssertNil(t, err)
+	testGet(t, c, "s", "k", "3")
+	tAssertEqual(t, []string{"3"}, c.GetValues("s", "k"))
+	tAssertEqual(t, []string{"other", "k"}, c.GetSectionKeyList("s"))
+	c.AddSectionKey("s", "k", "4")
+	buf.Reset()
+	tAssertNil(t, c.WriteTo(&buf, ""))
+	tAssertEqual(t, strings.Replace(data, "k = 3", "k = 4", 1), buf.String())
+
+	c, err = LoadFrom(strings.NewReader(data), &Options{DuplicateKeys: DuplicateFirst})
+	tAssertNil(t, err)
+	testGet(t, c, "s", "k", "1")
+	testGet(t, c, "s", "other", "x")
+	buf.Reset()
+	tAssertNil(t, c.WriteTo(&buf, ""))
+	tAssertEqual(t, data, buf.String())
+
+	c, err = LoadFrom(strings.NewReader(data), &Options{DuplicateKeys: DuplicateKeep})
+	tAssertNil(t, err)
+	testGet(t, c, "s", "k", "3")
+	tAssertEqual(t, []string{"1", "2\ncontinued", "3"}, c.GetValues("s", "k"))
+	tAssertEqual(t, []string{"x"}, c.GetValues("s", "other"))
+	tAssertEqual(t, []string(nil), c.GetValues("s", "missing"))
+	c.AddSectionKey("s", "k", "4")
+	tAssertEqual(t, []string{"4"}, c.GetValues("s", "k"))
+
+	_, err = LoadFrom(strings.NewReader(data), &Options{DuplicateKeys: DuplicateError})
+	var pe *ParseError
+	tAssertTrue(t, errors.As(err, &pe))
+	tAssertEqual(t, 3, pe.Line)
+	tAssertEqual(t, "duplicate option", pe.Reason)
+
+	_, err = LoadFrom(strings.NewReader(data), &Options{DuplicateKeys: DuplicateError, AllErrors: true})
+	var pes ParseErrors
+	tAssertTrue(t, errors.As(err, &pes))
+	tAssertEqual(t, 2, len(pes))
+	tAssertEqual(t, 6, pes[1].Line)
+}
+
+func TestLists(t *testing.T) {
+	data := `[s]
+array_key = 1,2,3,4,5
+floats = 1.5; 2
+bools = true, no, 1
+durations = 1s, 1m30s
+hosts = a.example.com, "b, c.example.com", " d ", "say \"hi\""
+map = k1=v1, k2 = v2, "k=3" = "v,3", k4
+empty =
+`
+	c, err := LoadFromData([]byte(data))
+	tAssertNil(t, err)
+
+	ints, err := c.GetInts("s", "array_key", ",")
+	tAssertNil(t, err)
+	tAssertEqual(t, []int{1, 2, 3, 4, 5}, ints)
+	tAssertEqual(t, []string{"1", "2", "3", "4", "5"}, c.MustStrings("s", "array_key", ","))
+	tAssertEqual(t, []float64{1.5, 2}, c.MustFloat64s("s", "floats", ";"))
+	tAssertEqual(t, []bool{true, false, true}, c.MustBools("s", "bools", ","))
+	tAssertEqual(t, []time.Duration{time.Second, 90 * time.Second}, c.MustDurations("s", "durations", ","))
+	tAssertEqual(t, []string{"a.example.com", "b, c.example.com", " d ", `say "hi"`}, c.MustStrings("s", "hosts", ","))
+	tAssertEqual(t, map[string]string{"k1": "v1", "k2": "v2", "k=3": "v,3", "k4": ""}, c.MustStringMap("s", "map", ","))
+	tAssertEqual(t, []string(nil), c.MustStrings("s", "empty", ","))
+	tAssertEqual(t, map[string]string{}, c.MustStringMap("s", "empty", ","))
+
+	_, err = c.GetInts("s", "floats", ";")
+	tAssertNotNil(t, err)
+	tAssertEqual(t, []int{7}, c.MustInts("s", "floats", ";", []int{7}))
+	tAssertEqual(t, []int{8}, c.MustInts("s", "missing", ",", []int{8}))
+
+	c.SetStrings("s", "hosts2", c.MustStrings("s", "hosts", ","), ",")
+	testGet(t, c, "s", "hosts2", `a.example.com,"b, c.example.com"," d ","say \"hi\""`)
+	tAssertEqual(t, c.MustStrings("s", "hosts", ","), c.MustStrings("s", "hosts2", ","))
+	c.SetStringMap("s", "map2", c.MustStringMap("s", "map", ","), ", ")
+	testGet(t, c, "s", "map2", `k1=v1, k2=v2, k4="", "k=3"=v,3`)
+	tAssertEqual(t, c.MustStringMap("s", "map", ","), c.MustStringMap("s", "map2", ", "))
+
+	c.SetInts("s", "ints", []int{1, -2}, ",")
+	c.SetFloat64s("s", "floats", []float64{0.5, 3}, ",")
+	c.SetBools("s", "bools", []bool{true, false}, ",")
+	c.SetDurations("s", "durations", []time.Duration{time.Millisecond}, ",")
+	testGet(t, c, "s", "ints", "1,-2")
+	testGet(t, c, "s", "floats", "0.5,3")
+	testGet(t, c, "s", "bools", "true,false")
+	testGet(t, c, "s", "durations", "1ms")
+
+	// the values of the setters are read back from the saved file
+	values := []string{"a ;b", "c #d", "#e", ";f", "g\t;h", `i \"j\"`, ""}
+	c.SetStrings("s", "strings", values, ",")
+	c.SetStrings("s", "strings2", values, ", ")
+	c.SetStringMap("s", "map3", map[string]string{"#k": "v ;1", "k2": "#v"}, ", ")
+
+	var b bytes.Buffer
+	tAssertNil(t, c.WriteTo(&b, ""))
+	c, err = LoadFrom(&b, nil)
+	tAssertNil(t, err)
+	tAssertEqual(t, values, c.MustStrings("s", "strings", ","))
+	tAssertEqual(t, values, c.MustStrings("s", "strings2", ", "))
+	tAssertEqual(t, map[string]string{"#k": "v ;1", "k2": "#v"}, c.MustStringMap("s", "map3", ", "))
+	tAssertEqual(t, c.MustStrings("s", "hosts", ","), c.MustStrings("s", "hosts2", ","))
+}
+
+func TestScalarTypes(t *testing.T) {
+	data := `[s]
+int64 = -9000000000
+hex = 0x1F
+octal = 0o17
+leading = 017
+uint64 = 18446744073709551615
+timeout = 1m30s
+time = 2023-01-02T15:04:05Z
+size = 512MiB
+size2 = 1.5 GB
+size3 = 10
+url = https://example.com/path?q=1
+relative = /path
+mode = 0755
+sticky = 1777
+ip = 192.168.0.1
+ip6 = ::1
+bad = x
+`
+	c, err := LoadFromData([]byte(data))
+	tAssertNil(t, err)
+
+	tAssertEqual(t, int64(-9000000000), c.MustInt64("s", "int64"))
+	tAssertEqual(t, int64(31), c.MustInt64("s", "hex"))
+	tAssertEqual(t, int64(15), c.MustInt64("s", "octal"))
+	tAssertEqual(t, int64(17), c.MustInt64("s", "leading"))
+	tAssertEqual(t, uint64(18446744073709551615), c.MustUint64("s", "uint64"))
+	tAssertEqual(t, 90*time.Second, c.MustDuration("s", "timeout"))
+	tAssertEqual(t, time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC), c.MustTime("s", "time", time.RFC3339))
+	tAssertEqual(t, uint64(512<<20), c.MustByteSize("s", "size"))
+	tAssertEqual(t, uint64(1500000000), c.MustByteSize("s", "size2"))
+	tAssertEqual(t, uint64(10), c.MustByteSize("s", "size3"))
+	tAssertEqual(t, "example.com", c.MustURL("s", "url").Host)
+	tAssertEqual(t, os.FileMode(0755), c.MustFileMode("s", "mode"))
+	tAssertEqual(t, os.ModeSticky|os.ModePerm, c.MustFileMode("s", "sticky"))
+	tAssertEqual(t, "192.168.0.1", c.MustIP("s", "ip").String())
+	tAssertEqual(t, "::1", c.MustIP("s", "ip6").String())
+
+	var ve *ValueError
+	_, err = c.GetDuration("s", "bad")
+	tAssertTrue(t, errors.As(err, &ve))
+	tAssertEqual(t, "duration", ve.Type)
+	tAssertEqual(t, `ini: option 'bad' in section 's' is not a valid duration: "x"`, err.Error())
+	_, err = c.GetURL("s", "relative")
+	tAssertTrue(t, errors.As(err, &ve))
+	_, err = c.GetByteSize("s", "int64")
+	tAssertTrue(t, errors.As(err, &ve))
+	_, err = c.GetInt64("s", "uint64")
+	tAssertTrue(t, errors.Is(err, strconv.ErrRange))
+	_, err = c.GetIP("s", "missing")
+	tAssertTrue(t, errors.Is(err, ErrKeyNotFound))
+	tAssertFalse(t, errors.As(err, &ve))
+
+	tAssertEqual(t, 5*time.Second, c.MustDuration("s", "bad", 5*time.Second))
+	tAssertEqual(t, os.FileMode(0644), c.MustFileMode("s", "bad", 0644))
+}
+
+func TestConstrainedValues(t *testing.T) {
+	data := `[s]
+level = Debug
+port = 8080
+ratio = 0.25
+name = web-01
+`
+	c, err := LoadFromData([]byte(data))
+	tAssertNil(t, err)
+
+	levels := []string{"debug", "info", "error"}
+	_, err = c.GetEnum("s", "level", levels)
+	var ve *ValueError
+	tAssertTrue(t, errors.Is(err, ErrNotAllowed))
+	tAssertTrue(t, errors.As(err, &ve))
+	tAssertEqual(t, `ini: option 'level' in section 's' is not a valid value: "Debug" (allowed: "debug", "info", "error")`, err.Error())
+	tAssertEqual(t, "info", c.MustEnum("s", "level", levels, "info"))
+	tAssertEqual(t, "info", c.MustValueRange("s", "level", "info", levels))
+
+	tAssertEqual(t, 8080, c.MustIntRange("s", "port", 1, 65535))
+	_, err = c.GetIntRange("s", "port", 1, 1024)
+	tAssertTrue(t, errors.Is(err, ErrNotAllowed))
+	tAssertEqual(t, `ini: option 'port' in section 's' is not a valid int: "8080" (allowed: [1, 1024])`, err.Error())
+	_, err = c.GetIntRange("s", "name", 1, 1024)
+	tAssertFalse(t, errors.Is(err, ErrNotAllowed))
+	tAssertTrue(t, errors.As(err, &ve))
+	tAssertEqual(t, 80, c.MustIntRange("s", "port", 1, 1024, 80))
+
+	tAssertEqual(t, 0.25, c.MustFloat64Range("s", "ratio", 0, 1))
+	tAssertEqual(t, 0.5, c.MustFloat64Range("s", "ratio", 0.5, 1, 0.5))
+
+	re := regexp.MustCompile(`^[a-z]+-[0-9]+$`)
+	tAssertEqual(t, "web-01", c.MustMatch("s", "name", re))
+	_, err = c.GetMatch("s", "level", re)
+	tAssertTrue(t, errors.Is(err, ErrNotAllowed))
+	tAssertEqual(t, "web-00", c.MustMatch("s", "level", re, "web-00"))
+
+	c, err = LoadFrom(strings.NewReader(data), &Options{EnumIgnoreCase: true})
+	tAssertNil(t, err)
+	tAssertEqual(t, "debug", c.MustEnum("s", "level", levels))
+}
+
+func TestOrigin(t *testing.T) {
+	c, err := LoadConfigFile("testdata/conf.ini", "testdata/conf2.ini")
+	tAssertNil(t, err)
+
+	o, err := c.Origin("Demo", "quote")
+	tAssertNil(t, err)
+	tAssertEqual(t, Origin{Source: "testdata/conf.ini", Line: lineOf(t, "testdata/conf.ini", "quote"), Section: "Demo"}, o)
+
+	o, err = c.Origin("Demo", "key2")
+	tAssertNil(t, err)
+	tAssertEqual(t, Origin{Source: "testdata/conf2.ini", Line: lineOf(t, "testdata/conf2.ini", "key2"), Layer: 1, Section: "Demo"}, o)
+
+	c.MergeFrom(New(nil))
+	c.AddSectionKey("Demo", "key2", "set")
+	o, err = c.Origin("Demo", "key2")
+	tAssertNil(t, err)
+	tAssertEqual(t, Origin{Layer: 2, Section: "Demo"}, o)
+
+	c.AddSectionKey("", "shared", "x")
+	o, err = c.Origin("Demo", "shared")
+	tAssertNil(t, err)
+	tAssertEqual(t, DEFAULT_SECTION, o.Section)
+
+	_, err = c.Origin("Demo", "missing")
+	tAssertTrue(t, errors.Is(err, ErrKeyNotFound))
+}
+
+// lineOf returns the line number of the first line of the file starting with
+// prefix.
+func lineOf(t *testing.T, fname, prefix string) int {
+	data, err := os.ReadFile(fname)
+	tAssertNil(t, err)
+	for i, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, prefix) {
+			return i + 1
+		}
+	}
+	t.Fatalf("%s: no line starting with %q", fname, prefix)
+	return 0
+}
+
+func TestMergeWith(t *testing.T) {
+	base := `[server]
+host = localhost
+port = 80
+debug = true
+[cache]
+size = 1MiB
+[log]
+file = /var/log/app.log
+level = info
+`
+	override := `[server]
+!debug
+port = 8080
+[-cache]
+[-log]
+level = error
+[new]
+b = 2
+a = 1
+`
+	opt := &Options{Tombstones: true}
+	p, err := LoadFrom(strings.NewReader(base), opt)
+	tAssertNil(t, err)
+	source, err := LoadFrom(strings.NewReader(override), opt)
+	tAssertNil(t, err)
+
+	// The markers are kept when written back
+	var buf bytes.Buffer
+	tAssertNil(t, source.WriteTo(&buf, ""))
+	tAssertEqual(t, override, buf.String())
+
+	p.MergeFrom(source)
+	tAssertEqual(t, []string{DEFAULT_SECTION, "server", "log", "new"}, p.GetSectionList())
+	tAssertEqual(t, []string{"host", "port"}, p.GetSectionKeyList("server"))
+	tAssertEqual(t, []string{"level"}, p.GetSectionKeyList("log"))
+	tAssertEqual(t, []string{"b", "a"}, p.GetSectionKeyList("new"))
+	testGet(t, p, "server", "port", "8080")
+	testGet(t, p, "log", "level", "error")
+
+	// Without the option, "[-cache]" is a section name
+	source, err = LoadFrom(strings.NewReader("[-cache]\n"), nil)
+	tAssertNil(t, err)
+	tAssertTrue(t, source.HasSection("-cache"))
+
+	// Replace sections
+	p, err = LoadFrom(strings.NewReader(base), nil)
+	tAssertNil(t, err)
+	source, err = LoadFrom(strings.NewReader("[server]\nport = 8080\n[cache]\n"), nil)
+	tAssertNil(t, err)
+	p.MergeWith(source, &MergeOptions{Mode: ReplaceSections})
+	tAssertEqual(t, []string{"port"}, p.GetSectionKeyList("server"))
+	tAssertEqual(t, []string{"size"}, p.GetSectionKeyList("cache"))
+
+	// Append values
+	keep := &Options{DuplicateKeys: DuplicateKeep}
+	p, err = LoadFrom(strings.NewReader("[s]\nk = 1\nk = 2\nother = x\n"), keep)
+	tAssertNil(t, err)
+	source, err = LoadFrom(strings.NewReader("[s]\nk = 3\nother = y\n"), keep)
+	tAssertNil(t, err)
+	p.MergeWith(source, &MergeOptions{AppendValues: true})
+	tAssertEqual(t, []string{"1", "2", "3"}, p.GetValues("s", "k"))
+	tAssertEqual(t, []string{"x", "y"}, p.GetValues("s", "other"))
+	testGet(t, p, "s", "k", "3")
+}
@@ -6,6 +6,7 @@ package ini
 
 import (
 	"regexp"
+	"sync"
 )
 
 const (
@@ -21,11 +22,62 @@ const (
 	_DEPTH_VALUES = 64
 )
 
+// DuplicateKeys is the policy for an option which is set more than once in a
+// section of the source, see Options.DuplicateKeys.
+type DuplicateKeys int
+
+const (
+	DuplicateLast  DuplicateKeys = iota // the last value wins
+	DuplicateFirst                      // the first value wins
+	DuplicateError                      // a duplicate option is a parse error
+	DuplicateKeep                       // the last value wins, all values are kept, see GetValues
+)
+
+// DefaultsMode is how the options added by ApplyDefaults are written, see
+// Options.Defaults.
+type DefaultsMode int
+
+const (
+	WriteDefaults   DefaultsMode = iota // written as the other options
+	OmitDefaults                        // not written
+	CommentDefaults                     // written as comments, e.g. "# port = 8080"
+)
+
 type Options struct {
 	Comment   string // default is DEFAULT_COMMENT
 	Separator string // default is ALTERNATIVE_SEPARATOR
 	PreSpace  bool   // default is true
 	PostSpace bool   // default is true
+	AllErrors bool   // report all parse errors, not just the first one
+	BlockMode bool   // make the Config safe for concurrent use, see Config.BlockMode
+	Goconfig  bool   // semantics of github.com/Unknwon/goconfig, see LoadConfigFile
+
+	RefSeparator  string // separator of %(section.key)s references, default is "."
+	NoSectionRefs bool   // disable %(section.key)s and ${section:key} references
+	NoInterpolate bool   // disable the unfolding of values, see GetString
+	Subsections   bool   // read [section "subsection"] headers, see SubsectionName
+
+	SectionSeparator string // separator of "parent.child" section names, default is "."
+	Inherit          bool   // look up missing options in the parent sections, see GetValue
+
+	DuplicateKeys  DuplicateKeys // policy for duplicate options, default is DuplicateLast
+	Tombstones     bool          // read "!key" and "[-section]" deletion markers, see MergeWith
+	EnumIgnoreCase bool          // ignore the case of the values of GetEnum
+	Defaults       DefaultsMode  // how the options added by ApplyDefaults are written
+
+	// Includes enables the include directives, see Load. It must not be set
+	// for untrusted input, which could then read any file.
+	Includes        bool
+	MaxIncludeDepth int                         // maximum depth of nested includes, default is 10
+	IncludeIf       func(condition string) bool // condition of the [includeIf "condition"] sections
+
+	// FileResolver registers the ${file:path} resolver, see RegisterResolver.
+	// Like Includes, it must not be set for untrusted input.
+	FileResolver bool
+
+	// LookupEnv looks up the environment variables of ${envvar} references,
+	// default is os.LookupEnv.
+	LookupEnv func(key string) (string, bool)
 }
 
 var (
@@ -49,28 +101,95 @@ var (
 		"不": false,
 	}
 
-	varRegExp    = regexp.MustCompile(`%\(([a-zA-Z0-9_.\-]+)\)s`) // %(variable)s
-	envVarRegExp = regexp.MustCompile(`\${([a-zA-Z0-9_.\-]+)}`)   // ${envvar}
+	varRegExp         = newVarRegExp(".")                                          // %(variable)s
+	goconfigVarRegExp = regexp.MustCompile(`^%\(([^)]+)\)s`)                       // %(variable)s of goconfig, any name
+	envNameRegExp     = regexp.MustCompile(`^[a-zA-Z0-9_.\-]+$`)                   // name of ${envvar}
+	envModRegExp      = regexp.MustCompile(`(?s)^([a-zA-Z0-9_.]+)(:?[-=?+])(.*)$`) // ${envvar:-default}
 )
 
 // Config is the representation of configuration settings.
 type Config struct {
+	// BlockMode makes the Config safe for concurrent use by guarding all
+	// reads and writes with a RWMutex. It is set by Options.BlockMode and
+	// must not be changed while the Config is in use.
+	BlockMode bool
+	mu        sync.RWMutex
+
+	options   Options // options used by New, reused when loading more files
 	comment   string
 	separator string
+	varRegExp *regexp.Regexp
+	resolvers map[string]func(ref string) (string, error) // scheme : resolver
+
+	// Source files, in load order
+	files   []string
+	watched []string  // source files and included files, in read order, see Watch
+	stats   fileStats // state of the watched files when read
+
+	// Reload callbacks
+	onChange []func(diff Diff)
+	onError  []func(err error)
 
 	// Sections order
-	lastIdSection   int            // Last section identifier
-	idSectionMap    map[string]int // Section : position
-	lastIdOptionMap map[string]int // Section : last identifier
+	sections      []string            // Sections, in order
+	idSectionMap  map[string]int      // Section : position in sections
+	optionListMap map[string][]string // Section : options, in order
+	autoKeys      map[string]int      // Section : number of the last "-" option
 
 	// Section -> option : value
 	dataMap map[string]map[string]*tValue
+	posMap  map[string]srcPos // Section : position of the header in the source
+	layer   int               // number of MergeFrom calls, see Origin
+
+	tombstones []tombstone // deletion markers, in order
+	includes   []string    // absolute names of the files being read, see Options.Includes
+
+	// Source text, kept to write the file back unchanged
+	raw           bool                 // read from a source
+	bom           bool                 // source starts with an UTF-8 BOM
+	eol           bool                 // last source line ends with a newline
+	newline       string               // line ending
+	rawSectionMap map[string][]string  // Section : comment lines and header line
+	overwritten   map[segment][]string // lines of overwritten options after an option, or after the header for ""
+	tail          []string             // comment lines after the last option
 }
 
 // tValue holds the input position for a value.
 type tValue struct {
-	position int    // Option order
+	position int    // Option order, position in optionListMap
 	v        string // value
+
+	// Source text of the option, if any
+	comments []string // comment lines before the option
+	lines    []string // option line and continuation lines
+	rawv     string   // value parsed from lines
+	values   []string // all values read, with DuplicateKeep
+	pos      srcPos   // position of the option line
+	prefix   string   // text of the option line before the value
+	suffix   string   // text of the option line after the value
+
+	defaulted bool // added by ApplyDefaults
+	included  bool // read from an included file
+	auto      bool // "-" option, numbered by autoKey
+
+	// Source text of a repeated header of the section before the option,
+	// which starts another part of the section
+	header []string // comment lines and header line
+	after  segment  // part of the source the header follows
+}
+
+// segment is a part of a section in the source: the options after the
+// header of the section, or after a repeated header, see tValue.header.
+type segment struct {
+	section string
+	option  string // first option after the repeated header, empty for the header of the section
+}
+
+// srcPos is a position in the source of a configuration.
+type srcPos struct {
+	source string // file name, empty if read from an io.Reader
+	line   int    // 1-based line number, 0 if not read
+	layer  int    // merge layer, see Origin
 }
 
 // New creates an empty configuration representation.
@@ -81,7 +200,6 @@ type tValue struct {
 //	opt.Separator: has to be `DEFAULT_SEPARATOR` or `ALTERNATIVE_SEPARATOR`
 //	opt.PreSpace: indicate if is inserted a space before of the separator
 //	opt.PostSpace: indicate if is added a space after of the separator
-//
 func New(opt *Options) *Config {
 	if opt == nil {
 		opt = &Options{
@@ -118,11 +236,41 @@ func New(opt *Options) *Config {
 
 	c := new(Config)
 
+	if opt.RefSeparator == "" {
+		opt.RefSeparator = "."
+	}
+	if opt.MaxIncludeDepth == 0 {
+		opt.MaxIncludeDepth = 10
+	}
+	if opt.SectionSeparator == "" {
+		opt.SectionSeparator = "."
+	}
+
+	c.BlockMode = opt.BlockMode
+	c.options = *opt
 	c.comment = comment
 	c.separator = separator
+	c.varRegExp = varRegExp
+	switch {
+	case opt.Goconfig:
+		c.varRegExp = goconfigVarRegExp
+	case !opt.NoSectionRefs && opt.RefSeparator != ".":
+		c.varRegExp = newVarRegExp(opt.RefSeparator)
+	}
+	c.resolvers = map[string]func(ref string) (string, error){
+		"env": envResolver(opt.LookupEnv),
+	}
+	if opt.FileResolver {
+		c.resolvers["file"] = resolveFile
+	}
+	c.newline = "\r\n"
 	c.idSectionMap = make(map[string]int)
-	c.lastIdOptionMap = make(map[string]int)
+	c.optionListMap = make(map[string][]string)
+	c.autoKeys = make(map[string]int)
 	c.dataMap = make(map[string]map[string]*tValue)
+	c.rawSectionMap = make(map[string][]string)
+	c.overwritten = make(map[segment][]string)
+	c.posMap = make(map[string]srcPos)
 
 	c.AddSection(DEFAULT_SECTION) // Default section always exists.
 
@@ -134,15 +282,37 @@ func New(opt *Options) *Config {
 // Merging means that any option (under any section) from source that is not in
 // p will be copied into p. When the p already has an option with
 // the same name and section then it is overwritten (i.o.w. the source wins).
+// New sections and options are added in the order of source, after the
+// existing ones.
 //
+// Each merge is a new layer of the configuration: the merged options keep
+// their source and line, with the number of the layer, see Origin.
+//
+// It is the same as MergeWith with the default MergeOptions.
 func (p *Config) MergeFrom(source *Config) {
-	if source == nil || len(source.dataMap) == 0 {
-		return
+	p.MergeWith(source, nil)
+}
+
+func (c *Config) lock() {
+	if c.BlockMode {
+		c.mu.Lock()
 	}
+}
 
-	for section, option := range source.dataMap {
-		for optionName, optionValue := range option {
-			p.AddSectionKey(section, optionName, optionValue.v)
-		}
+func (c *Config) unlock() {
+	if c.BlockMode {
+		c.mu.Unlock()
+	}
+}
+
+func (c *Config) rlock() {
+	if c.BlockMode {
+		c.mu.RLock()
+	}
+}
+
+func (c *Config) runlock() {
+	if c.BlockMode {
+		c.mu.RUnlock()
 	}
 }
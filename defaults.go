@@ -0,0 +1,76 @@
+// Copyright 2016 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ini
+
+// ApplyDefaults adds the options of defaults which are missing in the
+// configuration, with the sections, and tags them as defaulted. Unlike
+// MergeFrom it never overwrites an option: an option is missing if GetValue
+// fails, so options of the DEFAULT section, or of the parent sections with
+// Options.Inherit, are not overwritten either.
+//
+// Options.Defaults sets how the defaulted options are written, e.g. as
+// comments to generate a sample configuration. A defaulted option which is
+// set again is no longer defaulted.
+func (c *Config) ApplyDefaults(defaults *Config) {
+	if defaults == nil || defaults == c {
+		return
+	}
+
+	defaults = defaults.copyOf()
+	c.lock()
+	defer c.unlock()
+
+	for _, section := range defaults.sections {
+		for _, option := range defaults.optionListMap[section] {
+			if _, err := c.getValue(section, option); err == nil {
+				continue
+			}
+			c.addSectionKey(section, option, defaults.dataMap[section][option].v)
+			c.dataMap[section][option].defaulted = true
+		}
+	}
+}
+
+// IsDefaulted reports whether the option of the section was added by
+// ApplyDefaults and not set since.
+func (c *Config) IsDefaulted(section string, option string) bool {
+	c.rlock()
+	defer c.runlock()
+
+	if section == "" {
+		section = DEFAULT_SECTION
+	}
+	tValue, ok := c.dataMap[section][option]
+	return ok && tValue.defaulted
+}
+
+// Defaults returns a configuration with the default values of the options
+// of the schema, for ApplyDefaults.
+func (s *Schema) Defaults() *Config {
+	c := New(nil)
+	for _, ss := range s.Sections {
+		for _, k := range ss.Keys {
+			if k.Default != "" {
+				c.AddSectionKey(ss.Name, k.Name, k.Default)
+			}
+		}
+	}
+	return c
+}
+
+// isDefaultedSection reports whether the section was only added by
+// ApplyDefaults: it was not read, and all its options are defaulted.
+func (c *Config) isDefaultedSection(section string) bool {
+	if _, ok := c.rawSectionMap[section]; ok {
+		return false
+	}
+	options := c.optionListMap[section]
+	for _, option := range options {
+		if !c.dataMap[section][option].defaulted {
+			return false
+		}
+	}
+	return len(options) > 0
+}
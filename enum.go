@@ -0,0 +1,93 @@
+// Copyright 2016 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GetEnum has the same behaviour as String but checks that the response is
+// one of candidates. With Options.EnumIgnoreCase the case is ignored and the
+// matching candidate is returned.
+//
+// It returns a ValueError matching ErrNotAllowed, with the allowed values, if
+// the value is not one of candidates.
+func (c *Config) GetEnum(section string, option string, candidates []string) (value string, err error) {
+	err = c.getTyped(section, option, "value", func(s string) error {
+		for _, cand := range candidates {
+			if s == cand || c.options.EnumIgnoreCase && strings.EqualFold(s, cand) {
+				value = cand
+				return nil
+			}
+		}
+		return ErrNotAllowed
+	})
+	return value, withAllowed(err, quoteList(candidates))
+}
+
+// GetIntRange has the same behaviour as GetInt but checks that the response
+// is in [min, max].
+//
+// It returns a ValueError matching ErrNotAllowed if the value is out of range.
+func (c *Config) GetIntRange(section string, option string, min, max int) (value int, err error) {
+	err = c.getTyped(section, option, "int", func(s string) (err error) {
+		if value, err = strconv.Atoi(s); err == nil && (value < min || value > max) {
+			err = ErrNotAllowed
+		}
+		return err
+	})
+	return value, withAllowed(err, "["+strconv.Itoa(min)+", "+strconv.Itoa(max)+"]")
+}
+
+// GetFloat64Range has the same behaviour as GetFloat64 but checks that the
+// response is in [min, max].
+//
+// It returns a ValueError matching ErrNotAllowed if the value is out of range.
+func (c *Config) GetFloat64Range(section string, option string, min, max float64) (value float64, err error) {
+	err = c.getTyped(section, option, "float", func(s string) (err error) {
+		if value, err = strconv.ParseFloat(s, 64); err == nil && !(value >= min && value <= max) {
+			err = ErrNotAllowed
+		}
+		return err
+	})
+	return value, withAllowed(err, "["+
+		strconv.FormatFloat(min, 'g', -1, 64)+", "+strconv.FormatFloat(max, 'g', -1, 64)+"]",
+	)
+}
+
+// GetMatch has the same behaviour as String but checks that the response
+// matches re. Use an anchored expression, e.g. `^[a-z]+$`, to match the whole
+// value.
+//
+// It returns a ValueError matching ErrNotAllowed if the value does not match.
+func (c *Config) GetMatch(section string, option string, re *regexp.Regexp) (value string, err error) {
+	err = c.getTyped(section, option, "value", func(s string) error {
+		if !re.MatchString(s) {
+			return ErrNotAllowed
+		}
+		value = s
+		return nil
+	})
+	return value, withAllowed(err, "matching "+strconv.Quote(re.String()))
+}
+
+// withAllowed sets the allowed values of err if it is a ValueError.
+func withAllowed(err error, allowed string) error {
+	if e, ok := err.(*ValueError); ok {
+		e.Allowed = allowed
+	}
+	return err
+}
+
+// quoteList returns the quoted values separated by commas.
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,322 @@
+// Copyright 2016 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrSectionNotFound is returned when the section of an option does not
+	// exist and the option is not in the DEFAULT section either.
+	ErrSectionNotFound = errors.New("ini: section not found")
+
+	// ErrKeyNotFound is returned when an option does not exist.
+	ErrKeyNotFound = errors.New("ini: key not found")
+
+	// ErrInterpolationCycle is returned when unfolding a value cycled.
+	ErrInterpolationCycle = errors.New("ini: interpolation cycle")
+
+	// ErrUnresolvedReference is returned when a %(var)s or ${envvar}
+	// reference of a value cannot be resolved.
+	ErrUnresolvedReference = errors.New("ini: unresolved reference")
+
+	// ErrNotAllowed is returned when a value is not one of the allowed values,
+	// see GetEnum.
+	ErrNotAllowed = errors.New("ini: value not allowed")
+
+	// ErrUnknownSection and ErrUnknownKey are reported by Validate for the
+	// sections and options which are not in the schema.
+	ErrUnknownSection = errors.New("ini: unknown section")
+	ErrUnknownKey     = errors.New("ini: unknown key")
+
+	// ErrIncludeCycle is returned when a file includes itself, directly or
+	// not, see Options.Includes.
+	ErrIncludeCycle = errors.New("ini: include cycle")
+)
+
+// KeyError is returned when a section or an option does not exist.
+// Err is ErrSectionNotFound or ErrKeyNotFound.
+type KeyError struct {
+	Section string
+	Key     string
+	Err     error
+}
+
+func (e *KeyError) Error() string {
+	if e.Err == ErrSectionNotFound && e.Key == "" {
+		return fmt.Sprintf("ini: section '%s' not found", e.Section)
+	}
+	if e.Err == ErrSectionNotFound {
+		return fmt.Sprintf("ini: section '%s' not found (option '%s')", e.Section, e.Key)
+	}
+	return fmt.Sprintf("ini: option '%s' not found in section '%s'", e.Key, e.Section)
+}
+
+func (e *KeyError) Unwrap() error { return e.Err }
+
+// ValueError is returned when the value of an option cannot be converted to
+// the expected type. Err is the error of the conversion.
+type ValueError struct {
+	Section string
+	Key     string
+	Value   string // unfolded value
+	Type    string // expected type, e.g. "duration"
+	Allowed string // allowed values, if constrained, e.g. "[1, 10]"
+	Err     error
+}
+
+func (e *ValueError) Error() string {
+	if e.Allowed != "" {
+		return fmt.Sprintf("ini: option '%s' in section '%s' is not a valid %s: %q (allowed: %s)",
+			e.Key, e.Section, e.Type, e.Value, e.Allowed,
+		)
+	}
+	return fmt.Sprintf("ini: option '%s' in section '%s' is not a valid %s: %q",
+		e.Key, e.Section, e.Type, e.Value,
+	)
+}
+
+func (e *ValueError) Unwrap() error { return e.Err }
+
+// InterpolationError is returned when a value cannot be unfolded.
+// Err is ErrInterpolationCycle or ErrUnresolvedReference, or an error matching
+// ErrUnresolvedReference, such as a ResolverError.
+type InterpolationError struct {
+	Section string
+	Key     string
+	Chain   []string // references being unfolded, from the option to the one which failed
+	Err     error
+}
+
+func (e *InterpolationError) Error() string {
+	chain := strings.Join(e.Chain, " -> ")
+	switch e.Err {
+	case ErrInterpolationCycle:
+		return fmt.Sprintf("ini: cycle while unfolding option '%s' in section '%s': %s",
+			e.Key, e.Section, chain,
+		)
+	case ErrUnresolvedReference:
+		return fmt.Sprintf("ini: unresolved reference while unfolding option '%s' in section '%s': %s",
+			e.Key, e.Section, chain,
+		)
+	}
+	return fmt.Sprintf("ini: %v while unfolding option '%s' in section '%s': %s",
+		e.Err, e.Key, e.Section, chain,
+	)
+}
+
+func (e *InterpolationError) Unwrap() error { return e.Err }
+
+// ResolverError is returned by a resolver registered by RegisterResolver,
+// as the Err of an InterpolationError. It matches ErrUnresolvedReference and
+// the error of the resolver.
+type ResolverError struct {
+	Scheme string
+	Ref    string // unfolded reference passed to the resolver
+	Err    error
+}
+
+func (e *ResolverError) Error() string {
+	if e.Err == ErrUnresolvedReference {
+		return fmt.Sprintf("%s resolver: could not resolve %q", e.Scheme, e.Ref)
+	}
+	return fmt.Sprintf("%s resolver: %v", e.Scheme, e.Err)
+}
+
+// Unwrap returns ErrUnresolvedReference and the error of the resolver.
+func (e *ResolverError) Unwrap() []error {
+	return []error{ErrUnresolvedReference, e.Err}
+}
+
+// Is reports whether target is ErrUnresolvedReference or matches the error
+// of the resolver, as Unwrap does since Go 1.20.
+func (e *ResolverError) Is(target error) bool {
+	return target == ErrUnresolvedReference || errors.Is(e.Err, target)
+}
+
+// As finds the first error of the resolver which matches target, as Unwrap
+// does since Go 1.20.
+func (e *ResolverError) As(target interface{}) bool {
+	return e.Err != nil && errors.As(e.Err, target)
+}
+
+// Violation is a problem found by Validate.
+type Violation struct {
+	Source  string // file name, empty if read from an io.Reader
+	Line    int    // line of the option or the section header, 0 if unknown
+	Section string
+	Key     string // empty for a section
+
+	// Err is ErrSectionNotFound or ErrKeyNotFound for a missing required
+	// section or option, ErrUnknownSection or ErrUnknownKey, a *ValueError
+	// or an *InterpolationError.
+	Err error
+}
+
+func (v *Violation) Error() string {
+	var msg string
+	switch v.Err {
+	case ErrSectionNotFound:
+		msg = fmt.Sprintf("required section '%s' not found", v.Section)
+	case ErrKeyNotFound:
+		msg = fmt.Sprintf("required option '%s' not found in section '%s'", v.Key, v.Section)
+	case ErrUnknownSection:
+		msg = fmt.Sprintf("unknown section '%s'", v.Section)
+	case ErrUnknownKey:
+		msg = fmt.Sprintf("unknown option '%s' in section '%s'", v.Key, v.Section)
+	default:
+		msg = strings.TrimPrefix(v.Err.Error(), "ini: ")
+	}
+
+	if v.Line == 0 {
+		return "ini: " + msg
+	}
+	source := v.Source
+	if source == "" {
+		source = "<input>"
+	}
+	return fmt.Sprintf("ini: %s:%d: %s", source, v.Line, msg)
+}
+
+func (v *Violation) Unwrap() error { return v.Err }
+
+// ValidationError is the list of violations returned by Validate.
+type ValidationError []*Violation
+
+func (p ValidationError) Error() string {
+	switch len(p) {
+	case 0:
+		return "ini: no violations"
+	case 1:
+		return p[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more violations)", p[0], len(p)-1)
+}
+
+// Unwrap returns the violations, for errors.Is and errors.As.
+func (p ValidationError) Unwrap() []error {
+	errs := make([]error, len(p))
+	for i, v := range p {
+		errs[i] = v
+	}
+	return errs
+}
+
+// Is reports whether any of the violations matches target, as Unwrap does
+// since Go 1.20.
+func (p ValidationError) Is(target error) bool {
+	for _, v := range p {
+		if errors.Is(v, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the violations which matches target, as Unwrap does
+// since Go 1.20.
+func (p ValidationError) As(target interface{}) bool {
+	for _, v := range p {
+		if errors.As(v, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseError describes a line which could not be parsed.
+type ParseError struct {
+	Source string // file name, empty if read from an io.Reader
+	Line   int    // 1-based line number
+	Column int    // 1-based column, in bytes
+	Text   string // the line, as read
+	Reason string // what is wrong with the line
+	Err    error  // underlying error, if any, e.g. of an included file
+}
+
+func (e *ParseError) Error() string {
+	source := e.Source
+	if source == "" {
+		source = "<input>"
+	}
+	return fmt.Sprintf("ini: %s:%d:%d: could not parse line: %s: %q",
+		source, e.Line, e.Column, e.Reason, e.Text,
+	)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// ParseErrors is the list of parse errors returned by Load and LoadFrom
+// when Options.AllErrors is set.
+type ParseErrors []*ParseError
+
+func (p ParseErrors) Error() string {
+	switch len(p) {
+	case 0:
+		return "ini: no errors"
+	case 1:
+		return p[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", p[0], len(p)-1)
+}
+
+// Unwrap returns the errors, for errors.Is and errors.As.
+func (p ParseErrors) Unwrap() []error {
+	errs := make([]error, len(p))
+	for i, e := range p {
+		errs[i] = e
+	}
+	return errs
+}
+
+// Is reports whether any of the errors matches target, as Unwrap does since
+// Go 1.20.
+func (p ParseErrors) Is(target error) bool {
+	for _, e := range p {
+		if errors.Is(e, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors which matches target, as Unwrap does
+// since Go 1.20.
+func (p ParseErrors) As(target interface{}) bool {
+	for _, e := range p {
+		if errors.As(e, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// newParseError returns the error for the line text, which is l before the
+// comments were stripped; i is the index of the separator in l.
+func newParseError(source string, lineno int, text, l string, i int) *ParseError {
+	e := &ParseError{
+		Source: source,
+		Line:   lineno,
+		Column: len(text) - len(strings.TrimLeft(text, " \t")) + 1,
+		Text:   text,
+	}
+
+	switch {
+	case l[0] == ' ' || l[0] == '\t':
+		e.Reason = "continuation line without option"
+	case l[0] == '[':
+		e.Reason = "missing ']'"
+		e.Column = len(l) + 1
+	case i < 0:
+		e.Reason = "missing separator"
+	default:
+		e.Reason = "missing option name"
+		e.Column = i + 1
+	}
+	return e
+}
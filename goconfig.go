@@ -0,0 +1,412 @@
+// Copyright 2016 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+)
+
+// This file provides the API of github.com/Unknwon/goconfig on top of
+// Load, LoadFrom and MergeFrom.
+
+// LoadConfigFile loads one or more files in order; the options of a later
+// file overwrite the options of the earlier ones.
+//
+// The configuration has the semantics of goconfig, see Options.Goconfig:
+// it is safe for concurrent use, see Options.BlockMode, GetValue unfolds the
+// value, where %(name)s may name any option of the section, an option which is not in the "parent.child" section is looked up
+// in the "parent" section, see Options.Inherit, and MustValue, MustBool,
+// MustInt, MustFloat64 and MustInt64 return the zero value instead of
+// panicking if there is no default value.
+func LoadConfigFile(fileName string, moreFiles ...string) (c *Config, err error) {
+	if c, err = Load(fileName, goconfigOptions()); err != nil {
+		return nil, err
+	}
+	if err = c.AppendFiles(moreFiles...); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadFromData loads the configuration from the given data, with the
+// semantics of goconfig, see LoadConfigFile.
+func LoadFromData(data []byte) (c *Config, err error) {
+	return LoadFrom(bytes.NewReader(data), goconfigOptions())
+}
+
+// LoadFromReader loads the configuration from the given reader, with the
+// semantics of goconfig, see LoadConfigFile.
+func LoadFromReader(in io.Reader) (c *Config, err error) {
+	return LoadFrom(in, goconfigOptions())
+}
+
+// goconfigOptions returns the options of LoadConfigFile.
+func goconfigOptions() *Options {
+	return &Options{
+		Comment:   DEFAULT_COMMENT,
+		Separator: DEFAULT_SEPARATOR,
+		PreSpace:  true,
+		PostSpace: true,
+		BlockMode: true,
+		Goconfig:  true,
+		Inherit:   true,
+	}
+}
+
+// SaveConfigFile writes the configuration to the file.
+func SaveConfigFile(c *Config, fileName string) error {
+	return c.Save(fileName, "")
+}
+
+// SaveConfigData writes the configuration to the writer.
+func SaveConfigData(c *Config, out io.Writer) error {
+	return c.WriteTo(out, "")
+}
+
+// AppendFiles merges the given files into the configuration, in order.
+// The files are recorded so that Reload reads them again.
+func (c *Config) AppendFiles(files ...string) error {
+	for _, fname := range files {
+		c.rlock()
+		opt := c.options
+		c.runlock()
+
+		p, err := Load(fname, &opt)
+		if err != nil {
+			return err
+		}
+		c.MergeFrom(p)
+
+		c.lock()
+		c.files = append(c.files, fname)
+		c.watched = append(c.watched, p.watched...)
+		c.stats = append(c.stats, p.stats...)
+		c.unlock()
+	}
+	return nil
+}
+
+// Reload reloads all the files the configuration was loaded from.
+// The configuration is left untouched if any file fails to load.
+// The OnChange callbacks are called if the options changed.
+func (c *Config) Reload() error {
+	c.rlock()
+	files, opt := c.files, c.options
+	c.runlock()
+
+	if len(files) == 0 {
+		return errors.New("ini: configuration loaded from in-memory data, use ReloadData")
+	}
+
+	p, err := Load(files[0], &opt)
+	if err != nil {
+		return err
+	}
+	if err = p.AppendFiles(files[1:]...); err != nil {
+		return err
+	}
+
+	c.replaceWith(p)
+	return nil
+}
+
+// ReloadData reloads the configuration from the given reader.
+// It fails if the configuration was loaded from more than one file.
+func (c *Config) ReloadData(in io.Reader) error {
+	c.rlock()
+	files, opt := c.files, c.options
+	c.runlock()
+
+	if len(files) > 1 {
+		return errors.New("ini: multiple files loaded, unable to reload data")
+	}
+
+	p, err := LoadFrom(in, &opt)
+	if err != nil {
+		return err
+	}
+
+	c.replaceWith(p)
+	return nil
+}
+
+// replaceWith replaces the contents of c with the contents of p, and calls
+// the OnChange callbacks if the options changed.
+func (c *Config) replaceWith(p *Config) {
+	c.lock()
+	diff := c.diff(p)
+	onChange := c.onChange
+
+	c.files = p.files
+	c.watched = p.watched
+	c.stats = p.stats
+	c.sections = p.sections
+	c.idSectionMap = p.idSectionMap
+	c.optionListMap = p.optionListMap
+	c.autoKeys = p.autoKeys
+	c.dataMap = p.dataMap
+	c.raw = p.raw
+	c.bom = p.bom
+	c.eol = p.eol
+	c.newline = p.newline
+	c.rawSectionMap = p.rawSectionMap
+	c.overwritten = p.overwritten
+	c.posMap = p.posMap
+	c.layer = p.layer
+	c.tombstones = p.tombstones
+	c.tail = p.tail
+	c.unlock()
+
+	if len(diff) > 0 {
+		for _, fn := range onChange {
+			fn(diff)
+		}
+	}
+}
+
+// SetValue adds a new option and value to the configuration.
+// It is the same as AddSectionKey, but it returns false and does nothing if
+// the key is empty.
+func (c *Config) SetValue(section, key, value string) bool {
+	if key == "" {
+		return false
+	}
+	return c.AddSectionKey(section, key, value)
+}
+
+// GetKeyList returns the list of options in the section, as
+// GetSectionKeyList, or nil if the section does not exist.
+func (c *Config) GetKeyList(section string) []string {
+	c.rlock()
+	defer c.runlock()
+
+	if section == "" {
+		section = DEFAULT_SECTION
+	}
+	if _, ok := c.dataMap[section]; !ok {
+		return nil
+	}
+	return append([]string{}, c.optionListMap[section]...)
+}
+
+// DeleteKey is the same as RemoveSectionKey.
+func (c *Config) DeleteKey(section, key string) bool {
+	return c.RemoveSectionKey(section, key)
+}
+
+// DeleteSection is the same as RemoveSection, but it also removes the
+// DEFAULT section, whose name may be empty.
+func (c *Config) DeleteSection(section string) bool {
+	c.lock()
+	defer c.unlock()
+
+	if section == "" {
+		section = DEFAULT_SECTION
+	}
+	return c.deleteSection(section)
+}
+
+// GetSection returns the raw values of the options of the section, see
+// GetRawString.
+//
+// It returns an error if the section does not exist.
+func (c *Config) GetSection(section string) (map[string]string, error) {
+	c.rlock()
+	defer c.runlock()
+
+	if section == "" {
+		section = DEFAULT_SECTION
+	}
+	options, ok := c.dataMap[section]
+	if !ok {
+		return nil, &KeyError{Section: section, Err: ErrSectionNotFound}
+	}
+
+	values := make(map[string]string, len(options))
+	for option, tValue := range options {
+		values[option] = tValue.v
+	}
+	return values, nil
+}
+
+// GetSectionComments returns the comment lines before the header of the
+// section, joined by "\n", or "" if there are none.
+func (c *Config) GetSectionComments(section string) string {
+	c.rlock()
+	defer c.runlock()
+
+	if section == "" {
+		section = DEFAULT_SECTION
+	}
+	return getComments(c.rawSectionMap[section])
+}
+
+// SetSectionComments replaces the comment lines before the header of the
+// section with the lines of comments; a line which is not a comment is
+// prefixed with Options.Comment. The comments are removed if comments is
+// empty.
+//
+// It returns true if the comments were inserted or removed, and false if
+// they were overwritten, or if the section does not exist.
+func (c *Config) SetSectionComments(section, comments string) bool {
+	c.lock()
+	defer c.unlock()
+
+	if section == "" {
+		section = DEFAULT_SECTION
+	}
+	if _, ok := c.dataMap[section]; !ok {
+		return comments == ""
+	}
+
+	lines, ok := c.rawSectionMap[section]
+	if !ok && section != c.sections[0] {
+		lines = []string{c.newline, "[" + section + "]" + c.newline}
+	}
+	var header []string
+	if hasHeader(lines) {
+		lines, header = lines[:len(lines)-1], lines[len(lines)-1:]
+	}
+
+	lines, existed := c.setComments(lines, comments)
+	c.rawSectionMap[section] = append(lines, header...)
+	return comments == "" || !existed
+}
+
+// GetKeyComments returns the comment lines before the option of the section,
+// joined by "\n", or "" if there are none.
+func (c *Config) GetKeyComments(section, key string) string {
+	c.rlock()
+	defer c.runlock()
+
+	if section == "" {
+		section = DEFAULT_SECTION
+	}
+	if tValue, ok := c.dataMap[section][key]; ok {
+		return getComments(tValue.comments)
+	}
+	return ""
+}
+
+// SetKeyComments replaces the comment lines before the option of the
+// section, as SetSectionComments.
+//
+// It returns true if the comments were inserted or removed, and false if
+// they were overwritten, or if the option does not exist.
+func (c *Config) SetKeyComments(section, key, comments string) bool {
+	c.lock()
+	defer c.unlock()
+
+	if section == "" {
+		section = DEFAULT_SECTION
+	}
+	tValue, ok := c.dataMap[section][key]
+	if !ok {
+		return comments == ""
+	}
+
+	var existed bool
+	tValue.comments, existed = c.setComments(tValue.comments, comments)
+	return comments == "" || !existed
+}
+
+// getComments returns the comment lines of lines, joined by "\n".
+func getComments(lines []string) string {
+	var comments []string
+	for _, line := range lines {
+		if isComment(line) {
+			comments = append(comments, strings.TrimSpace(line))
+		}
+	}
+	return strings.Join(comments, "\n")
+}
+
+// setComments replaces the comment lines of lines with the lines of
+// comments, after the other lines, and reports whether there were comment
+// lines.
+func (c *Config) setComments(lines []string, comments string) (result []string, existed bool) {
+	for _, line := range lines {
+		if isComment(line) {
+			existed = true
+		} else {
+			result = append(result, line)
+		}
+	}
+	if comments == "" {
+		return result, existed
+	}
+
+	comments = strings.Replace(comments, "\r\n", "\n", -1)
+	for _, line := range strings.Split(comments, "\n") {
+		if !isComment(line) {
+			line = c.comment + line
+		}
+		result = append(result, line+c.newline)
+	}
+	return result, existed
+}
+
+// isComment reports whether the source line is a comment.
+func isComment(line string) bool {
+	line = strings.TrimSpace(line)
+	return line != "" && (line[0] == '#' || line[0] == ';')
+}
+
+// Bool is the same as GetBool.
+func (c *Config) Bool(section, key string) (bool, error) {
+	return c.GetBool(section, key)
+}
+
+// Int is the same as GetInt.
+func (c *Config) Int(section, key string) (int, error) {
+	return c.GetInt(section, key)
+}
+
+// Int64 is the same as GetInt64.
+func (c *Config) Int64(section, key string) (int64, error) {
+	return c.GetInt64(section, key)
+}
+
+// Float64 is the same as GetFloat64.
+func (c *Config) Float64(section, key string) (float64, error) {
+	return c.GetFloat64(section, key)
+}
+
+// MustValueSet always returns a value without error. It returns the default
+// value if the option does not exist or is empty, and a bool value indicates
+// whether the default value is returned.
+func (c *Config) MustValueSet(section, key string, defaultVal ...string) (string, bool) {
+	v, err := c.GetValue(section, key)
+	if len(defaultVal) > 0 && (err != nil || v == "") {
+		return defaultVal[0], true
+	}
+	return v, false
+}
+
+// MustValueRange always returns a value without error. It returns the default
+// value if the option does not exist or the value is not one of candidates,
+// see GetEnum.
+func (c *Config) MustValueRange(section, key, defaultVal string, candidates []string) string {
+	return c.MustEnum(section, key, candidates, defaultVal)
+}
+
+// MustValueArray always returns a value without error. It splits the value by
+// delim and trims the spaces of each element; it returns an empty list if the
+// option does not exist or is empty.
+func (c *Config) MustValueArray(section, key, delim string) []string {
+	v, err := c.GetValue(section, key)
+	if err != nil || v == "" {
+		return []string{}
+	}
+
+	vals := strings.Split(v, delim)
+	for i := range vals {
+		vals[i] = strings.TrimSpace(vals[i])
+	}
+	return vals
+}
@@ -0,0 +1,173 @@
+// Copyright 2016 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isInclude reports whether the option of the section, which is empty
+// before the first section header, is an include directive, and whether the
+// file has to be included, see Options.Includes.
+func (c *Config) isInclude(section, option string) (directive, ok bool) {
+	switch {
+	case !c.options.Includes:
+		return false, false
+	case section == "":
+		return option == "include", true
+	case option != "path":
+		return false, false
+	case section == "include":
+		return true, true
+	}
+	name, cond, isSub := SplitSubsection(section)
+	if !isSub || name != "includeIf" {
+		return false, false
+	}
+	return true, c.options.IncludeIf != nil && c.options.IncludeIf(cond)
+}
+
+// include reads the files matching the pattern into the configuration, as
+// if they were in the source at the include directive. A relative pattern is
+// relative to the directory of the source.
+func (c *Config) include(source, pattern string) error {
+	if !filepath.IsAbs(pattern) && source != "" {
+		pattern = filepath.Join(filepath.Dir(source), pattern)
+	}
+	files := []string{pattern}
+	if strings.ContainsAny(pattern, "*?[") {
+		var err error
+		if files, err = filepath.Glob(pattern); err != nil {
+			return err
+		}
+	}
+
+	for _, fname := range files {
+		abs, err := filepath.Abs(fname)
+		if err != nil {
+			return err
+		}
+		for i, f := range c.includes {
+			if f == abs {
+				chain := append(append([]string(nil), c.includes[i:]...), abs)
+				return fmt.Errorf("%w: %s", ErrIncludeCycle, strings.Join(chain, " -> "))
+			}
+		}
+		if len(c.includes) > c.options.MaxIncludeDepth {
+			return fmt.Errorf("ini: maximum include depth %d exceeded", c.options.MaxIncludeDepth)
+		}
+
+		if err := c.includeFile(fname, abs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// includeFile reads the file, whose absolute name is abs, into the
+// configuration. The options already set are handled as duplicate options,
+// see Options.DuplicateKeys.
+func (c *Config) includeFile(fname, abs string) error {
+	file, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	opt := c.options
+	p := New(&opt)
+	p.includes = append(c.includes[:len(c.includes):len(c.includes)], abs)
+	c.watch(fname, file)
+	if err = p.read(bufio.NewReader(file), fname); err != nil {
+		return err
+	}
+	c.watched = append(c.watched, p.watched...)
+	c.stats = append(c.stats, p.stats...)
+
+	var errs ParseErrors
+	for _, section := range p.sections {
+		if c.addSection(section) {
+			pos := p.posMap[section]
+			c.posMap[section] = srcPos{pos.source, pos.line, c.layer}
+		}
+		for _, option := range p.optionListMap[section] {
+			sv := p.dataMap[section][option]
+			var values []string
+			if tValue, ok := c.dataMap[section][option]; ok {
+				switch c.options.DuplicateKeys {
+				case DuplicateFirst:
+					continue
+				case DuplicateError:
+					e := &ParseError{Source: sv.pos.source, Line: sv.pos.line, Column: 1, Reason: "duplicate option"}
+					if len(sv.lines) > 0 { // not from a nested include
+						e.Text = strings.TrimRight(sv.lines[0], "\r\n")
+					}
+					errs = append(errs, e)
+					continue
+				case DuplicateKeep:
+					values = valuesOf(tValue)
+				}
+			}
+			c.addSectionKey(section, option, sv.v)
+
+			tValue := c.dataMap[section][option]
+			tValue.pos = srcPos{sv.pos.source, sv.pos.line, c.layer}
+			tValue.included = true
+			tValue.auto = sv.auto
+			if c.options.DuplicateKeys == DuplicateKeep {
+				tValue.values = append(values, valuesOf(sv)...)
+			}
+		}
+	}
+	c.tombstones = append(c.tombstones, p.tombstones...)
+
+	switch {
+	case len(errs) == 0:
+		return nil
+	case c.options.AllErrors:
+		return errs
+	}
+	return errs[0]
+}
+
+// includeErrors returns the parse errors of the error of the include
+// directive on the line: the errors of the included file, or an error of
+// the line.
+func includeErrors(source string, lineno int, text, l string, err error) ParseErrors {
+	var pes ParseErrors
+	var pe *ParseError
+	switch {
+	case errors.As(err, &pes):
+		return pes
+	case errors.As(err, &pe):
+		return ParseErrors{pe}
+	}
+
+	e := newParseError(source, lineno, text, l, 0)
+	e.Reason = "could not include: " + strings.TrimPrefix(err.Error(), "ini: ")
+	e.Column = 1
+	e.Err = err
+	return ParseErrors{e}
+}
+
+// isIncludedSection reports whether the section was only read from included
+// files: it was not read from the source, and all its options are included.
+func (c *Config) isIncludedSection(section string) bool {
+	if _, ok := c.rawSectionMap[section]; ok {
+		return false
+	}
+	options := c.optionListMap[section]
+	for _, option := range options {
+		if !c.dataMap[section][option].included {
+			return false
+		}
+	}
+	return len(options) > 0
+}
@@ -0,0 +1,154 @@
+// Copyright 2016 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, data string) string {
+		fname := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(fname), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fname, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return fname
+	}
+
+	writeFile("common.ini", "name = common\n[server]\nhost = localhost\nport = 80\n")
+	writeFile("conf.d/a.ini", "[a]\nkey = a\n")
+	writeFile("conf.d/b.ini", "[b]\nkey = b\n")
+	writeFile("local.ini", "[server]\nport = 8080\n")
+	writeFile("prod.ini", "[server]\nhost = example.com\n")
+	writeFile("dev.ini", "[server]\nhost = dev\n")
+	main := writeFile("main.ini", `include = common.ini
+[server]
+timeout = 30
+!include conf.d/*.ini
+
+[include]
+path = local.ini
+
+[includeIf "prod"]
+path = prod.ini
+
+[includeIf "dev"]
+path = dev.ini
+`)
+
+	// disabled by default
+	c, err := Load(writeFile("off.ini", "include = common.ini\n[include]\npath = local.ini\n"), nil)
+	tAssertNil(t, err)
+	testGet(t, c, DEFAULT_SECTION, "include", "common.ini")
+	testGet(t, c, "include", "path", "local.ini")
+	tAssertFalse(t, c.HasSection("server"))
+	_, err = Load(main, nil)
+	tAssertNotNil(t, err)
+
+	opt := &Options{
+		Includes:  true,
+		IncludeIf: func(cond string) bool { return cond == "prod" },
+	}
+	c, err = Load(main, opt)
+	tAssertNil(t, err)
+	testGet(t, c, DEFAULT_SECTION, "name", "common")
+	testGet(t, c, "server", "host", "example.com")
+	testGet(t, c, "server", "port", "8080")
+	testGet(t, c, "server", "timeout", "30")
+	testGet(t, c, "a", "key", "a")
+	testGet(t, c, "b", "key", "b")
+	tAssertFalse(t, c.HasSectionKey(DEFAULT_SECTION, "include"))
+	tAssertFalse(t, c.HasSectionKey("include", "path"))
+
+	o, err := c.Origin("server", "port")
+	tAssertNil(t, err)
+	tAssertEqual(t, filepath.Join(dir, "local.ini"), o.Source)
+	tAssertEqual(t, 2, o.Line)
+	o, err = c.Origin("server", "timeout")
+	tAssertNil(t, err)
+	tAssertEqual(t, main, o.Source)
+	tAssertEqual(t, 3, o.Line)
+
+	// the included options are not written back
+	var buf bytes.Buffer
+	tAssertNil(t, c.WriteTo(&buf, ""))
+	data, _ := os.ReadFile(main)
+	tAssertEqual(t, string(data), buf.String())
+
+	// cycles
+	writeFile("x.ini", "include = y.ini\n")
+	y := writeFile("y.ini", "[y]\n!include x.ini\n")
+	_, err = Load(y, opt)
+	tAssertTrue(t, errors.Is(err, ErrIncludeCycle))
+	var pe *ParseError
+	tAssertTrue(t, errors.As(err, &pe))
+	tAssertEqual(t, filepath.Join(dir, "x.ini"), pe.Source)
+	tAssertEqual(t, 1, pe.Line)
+
+	self := writeFile("self.ini", "!include self.ini\n")
+	_, err = Load(self, opt)
+	tAssertTrue(t, errors.Is(err, ErrIncludeCycle))
+
+	// depth
+	for i := 0; i < 3; i++ {
+		writeFile("deep"+string(rune('0'+i))+".ini", "!include deep"+string(rune('1'+i))+".ini\n")
+	}
+	writeFile("deep3.ini", "[deep]\nkey = 3\n")
+	c, err = Load(filepath.Join(dir, "deep0.ini"), &Options{Includes: true, MaxIncludeDepth: 3})
+	tAssertNil(t, err)
+	testGet(t, c, "deep", "key", "3")
+	_, err = Load(filepath.Join(dir, "deep0.ini"), &Options{Includes: true, MaxIncludeDepth: 2})
+	tAssertNotNil(t, err)
+	tAssertTrue(t, strings.Contains(err.Error(), "maximum include depth 2 exceeded"))
+
+	// duplicate options, included after and before the option
+	dup := writeFile("dup.ini", "[s]\nk=1\n")
+	after := writeFile("after.ini", "[s]\nk=2\n!include dup.ini\n")
+	before := writeFile("before.ini", "!include dup.ini\n[s]\nk=2\n")
+	for _, tt := range []struct {
+		mode                DuplicateKeys
+		after, before       string
+		afterAll, beforeAll []string
+	}{
+		{DuplicateLast, "1", "2", []string{"1"}, []string{"2"}},
+		{DuplicateFirst, "2", "1", []string{"2"}, []string{"1"}},
+		{DuplicateKeep, "1", "2", []string{"2", "1"}, []string{"1", "2"}},
+	} {
+		c, err = Load(after, &Options{Includes: true, DuplicateKeys: tt.mode})
+		tAssertNil(t, err)
+		testGet(t, c, "s", "k", tt.after)
+		tAssertEqual(t, tt.afterAll, c.GetValues("s", "k"))
+
+		c, err = Load(before, &Options{Includes: true, DuplicateKeys: tt.mode})
+		tAssertNil(t, err)
+		testGet(t, c, "s", "k", tt.before)
+		tAssertEqual(t, tt.beforeAll, c.GetValues("s", "k"))
+	}
+	_, err = Load(after, &Options{Includes: true, DuplicateKeys: DuplicateError})
+	tAssertTrue(t, errors.As(err, &pe))
+	tAssertEqual(t, &ParseError{Source: dup, Line: 2, Column: 1, Text: "k=1", Reason: "duplicate option"}, pe)
+	_, err = Load(before, &Options{Includes: true, DuplicateKeys: DuplicateError})
+	tAssertTrue(t, errors.As(err, &pe))
+	tAssertEqual(t, before, pe.Source)
+	tAssertEqual(t, 3, pe.Line)
+	tAssertEqual(t, "duplicate option", pe.Reason)
+
+	// missing file
+	missing := writeFile("missing.ini", "[m]\n!include nothing.ini\n")
+	_, err = Load(missing, opt)
+	tAssertTrue(t, errors.Is(err, os.ErrNotExist))
+	tAssertTrue(t, errors.As(err, &pe))
+	tAssertEqual(t, missing, pe.Source)
+	tAssertEqual(t, 2, pe.Line)
+}
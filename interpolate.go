@@ -0,0 +1,287 @@
+// Copyright 2016 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// newVarRegExp returns the regexp matching %(variable)s at the start of a
+// string, where the variable may contain the separator sep of qualified
+// references.
+func newVarRegExp(sep string) *regexp.Regexp {
+	class := `a-zA-Z0-9_.\-`
+	for _, r := range sep {
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsPunct(r) || unicode.IsSymbol(r) || r == ' '):
+			class += `\` + string(r)
+		default:
+			class += string(r)
+		}
+	}
+	return regexp.MustCompile(`^%\(([` + class + `]+)\)s`)
+}
+
+// interpolator unfolds the references of a value.
+type interpolator struct {
+	getValue   func(section, option string) (string, error) // raw value, see GetValue
+	hasSection func(section string) bool
+	lookupEnv  func(key string) (string, bool)
+	resolvers  map[string]func(ref string) (string, error)
+	varRegExp  *regexp.Regexp
+	refSep     string // separator of %(section.key)s, empty if disabled
+
+	chain  []string        // references being unfolded
+	active map[string]bool // options being unfolded, by section+"\x00"+option
+}
+
+func (c *Config) newInterpolator() *interpolator {
+	p := &interpolator{
+		getValue: c.getValue,
+		hasSection: func(section string) bool {
+			_, ok := c.dataMap[section]
+			return ok
+		},
+		lookupEnv: c.options.LookupEnv,
+		resolvers: c.resolvers,
+		varRegExp: c.varRegExp,
+		active:    make(map[string]bool),
+	}
+	if p.lookupEnv == nil {
+		p.lookupEnv = os.LookupEnv
+	}
+	if !c.options.NoSectionRefs {
+		p.refSep = c.options.RefSeparator
+	}
+	return p
+}
+
+// unfold unfolds the value of the option of the section.
+func (p *interpolator) unfold(section, option, value string) (string, error) {
+	p.active[section+"\x00"+option] = true
+	value, err := p.expand(section, value)
+	if err != nil {
+		return "", &InterpolationError{Section: section, Key: option, Chain: p.chain, Err: err}
+	}
+	return value, nil
+}
+
+// expand replaces the references of value, which is a value of the section.
+func (p *interpolator) expand(section, value string) (string, error) {
+	if !strings.ContainsAny(value, "%$") {
+		return value, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); {
+		switch s := value[i:]; {
+		// Escaped dollar and percent signs
+		case strings.HasPrefix(s, "$$"), strings.HasPrefix(s, `\$`):
+			b.WriteByte('$')
+			i += 2
+		case strings.HasPrefix(s, "%%"):
+			b.WriteByte('%')
+			i += 2
+
+		// ${envvar}
+		case strings.HasPrefix(s, "${"):
+			j := closingBrace(s)
+			if j < 0 {
+				b.WriteString(s)
+				i = len(value)
+				break
+			}
+			v, err := p.expandEnv(section, s[:j+1], s[2:j])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(v)
+			i += j + 1
+
+		// %(variable)s
+		case strings.HasPrefix(s, "%("):
+			m := p.varRegExp.FindStringSubmatchIndex(s)
+			if m == nil {
+				b.WriteByte(value[i])
+				i++
+				break
+			}
+			v, err := p.expandVar(section, s[:m[1]], s[m[2]:m[3]])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(v)
+			i += m[1]
+
+		default:
+			b.WriteByte(value[i])
+			i++
+		}
+	}
+	return b.String(), nil
+}
+
+// closingBrace returns the index of the '}' closing the "${" at the start of s,
+// or -1.
+func closingBrace(s string) int {
+	depth := 0
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// expandVar returns the value of the %(name)s reference ref.
+func (p *interpolator) expandVar(section, ref, name string) (string, error) {
+	if _, err := p.getValue(section, name); err != nil && p.refSep != "" {
+		for i := strings.LastIndex(name, p.refSep); i > 0; i = strings.LastIndex(name[:i], p.refSep) {
+			if p.hasSection(name[:i]) {
+				return p.expandRef(ref, name[:i], name[i+len(p.refSep):])
+			}
+		}
+	}
+	return p.expandRef(ref, section, name)
+}
+
+// expandEnv returns the value of the ${body} reference ref.
+func (p *interpolator) expandEnv(section, ref, body string) (string, error) {
+	if i := strings.Index(body, ":"); i > 0 && !strings.ContainsAny(body[i+1:i+2], "-=?+") {
+		if fn, ok := p.resolvers[body[:i]]; ok {
+			return p.expandResolver(section, ref, body[:i], body[i+1:], fn)
+		}
+	}
+	if p.refSep != "" {
+		for i := strings.Index(body, ":"); i >= 0; i = nextIndex(body, ":", i) {
+			if p.hasSection(body[:i]) && !strings.ContainsAny(body[i+1:i+2], "-=?+") {
+				return p.expandRef(ref, body[:i], body[i+1:])
+			}
+		}
+	}
+
+	isName := envNameRegExp.MatchString(body)
+	if isName {
+		if v, ok := p.lookupEnv(body); ok && v != "" {
+			return v, nil
+		}
+	}
+	if m := envModRegExp.FindStringSubmatch(body); m != nil {
+		return p.expandEnvMod(section, ref, m[1], m[2], m[3])
+	}
+
+	if isName || strings.Contains(body, ":") && p.refSep != "" {
+		p.chain = append(p.chain, ref)
+		return "", ErrUnresolvedReference
+	}
+	return ref, nil // not a reference
+}
+
+// expandEnvMod returns the value of the ${name<op>word} reference ref, as
+// the shell does; op is one of -, =, ? and +, with or without a leading ':'.
+// With the ':' an empty variable is handled as an unset one.
+func (p *interpolator) expandEnvMod(section, ref, name, op, word string) (string, error) {
+	v, ok := p.lookupEnv(name)
+	set := ok && (v != "" || op[0] != ':')
+
+	p.chain = append(p.chain, ref)
+	switch op[len(op)-1] {
+	case '-', '=':
+		if !set {
+			var err error
+			if v, err = p.expand(section, word); err != nil {
+				return "", err
+			}
+		}
+	case '?':
+		if !set {
+			msg, err := p.expand(section, word)
+			if err != nil {
+				return "", err
+			}
+			if msg == "" {
+				msg = "parameter null or not set"
+			}
+			return "", &unsetEnvError{name: name, msg: msg}
+		}
+	case '+':
+		v = ""
+		if set {
+			var err error
+			if v, err = p.expand(section, word); err != nil {
+				return "", err
+			}
+		}
+	}
+	p.chain = p.chain[:len(p.chain)-1]
+	return v, nil
+}
+
+// expandResolver returns the value of the ${scheme:arg} reference ref.
+func (p *interpolator) expandResolver(section, ref, scheme, arg string, fn func(string) (string, error)) (string, error) {
+	p.chain = append(p.chain, ref)
+	arg, err := p.expand(section, arg)
+	if err != nil {
+		return "", err
+	}
+	v, err := fn(arg)
+	if err != nil {
+		return "", &ResolverError{Scheme: scheme, Ref: arg, Err: err}
+	}
+	p.chain = p.chain[:len(p.chain)-1]
+	return v, nil
+}
+
+// unsetEnvError is the error of a ${name:?message} reference.
+type unsetEnvError struct {
+	name string
+	msg  string
+}
+
+func (e *unsetEnvError) Error() string { return e.name + ": " + e.msg }
+
+func (e *unsetEnvError) Unwrap() error { return ErrUnresolvedReference }
+
+// expandRef returns the unfolded value of the option referenced by ref.
+func (p *interpolator) expandRef(ref, section, option string) (string, error) {
+	p.chain = append(p.chain, ref)
+
+	id := section + "\x00" + option
+	if p.active[id] || len(p.chain) > _DEPTH_VALUES {
+		return "", ErrInterpolationCycle
+	}
+
+	v, err := p.getValue(section, option)
+	if err != nil {
+		return "", ErrUnresolvedReference
+	}
+
+	p.active[id] = true
+	v, err = p.expand(section, v)
+	if err != nil {
+		return "", err
+	}
+	delete(p.active, id)
+
+	p.chain = p.chain[:len(p.chain)-1]
+	return v, nil
+}
+
+// nextIndex returns the index of the next sep in s after index i, or -1.
+func nextIndex(s, sep string, i int) int {
+	if j := strings.Index(s[i+1:], sep); j >= 0 {
+		return i + 1 + j
+	}
+	return -1
+}
@@ -0,0 +1,235 @@
+// Copyright 2016 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"sync"
+)
+
+// Layered is a stack of configurations, e.g. the system, user, project and
+// override configurations, which are looked up as one without merging them.
+// An option of a higher layer hides the same option of the lower layers.
+//
+// A Layered is safe for concurrent use if its layers are, see BlockMode.
+type Layered struct {
+	mu     sync.RWMutex
+	layers []*Config // lowest priority first
+	write  *Config   // layer of SetValue, nil for the top layer
+}
+
+// NewLayered returns a stack of the layers, from the lowest priority to the
+// highest.
+func NewLayered(layers ...*Config) *Layered {
+	return &Layered{layers: append([]*Config(nil), layers...)}
+}
+
+// Layers returns the layers, from the lowest priority to the highest.
+func (l *Layered) Layers() []*Config {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return append([]*Config(nil), l.layers...)
+}
+
+// AddLayer adds the layer on top of the stack, with the highest priority.
+func (l *Layered) AddLayer(c *Config) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.layers = append(l.layers, c)
+}
+
+// RemoveLayer removes the layer from the stack.
+// It returns true if the layer was removed, and false if it was not in the stack.
+func (l *Layered) RemoveLayer(c *Config) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	i := l.index(c)
+	if i < 0 {
+		return false
+	}
+	l.layers = append(l.layers[:i], l.layers[i+1:]...)
+	if l.write == c {
+		l.write = nil
+	}
+	return true
+}
+
+// SetWriteLayer sets the layer changed by SetValue, which is the top layer by
+// default; nil resets the default. It returns false if the layer is not in
+// the stack.
+func (l *Layered) SetWriteLayer(c *Config) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if c != nil && l.index(c) < 0 {
+		return false
+	}
+	l.write = c
+	return true
+}
+
+// SetValue adds the option and value to the write layer, see SetWriteLayer,
+// which can then be saved by WriteFile. It returns the same as AddSectionKey,
+// and false if there are no layers.
+func (l *Layered) SetValue(section, option, value string) bool {
+	l.mu.RLock()
+	c := l.write
+	if c == nil && len(l.layers) > 0 {
+		c = l.layers[len(l.layers)-1]
+	}
+	l.mu.RUnlock()
+
+	if c == nil {
+		return false
+	}
+	return c.AddSectionKey(section, option, value)
+}
+
+// GetValue gets the (raw) string value for the given option in the section,
+// from the highest layer which has the option in the section, or else from
+// the highest layer which has the option in the DEFAULT section.
+//
+// It returns an error if either the section or the option do not exist.
+func (l *Layered) GetValue(section, option string) (string, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.getValue(section, option)
+}
+
+// GetString gets the value as GetValue, and unfolds it as Config.GetString.
+// The references are looked up in all the layers, as GetValue; the other
+// options of the unfolding, such as the resolvers, are those of the top layer.
+func (l *Layered) GetString(section, option string) (string, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if section == "" {
+		section = DEFAULT_SECTION
+	}
+	value, err := l.getValue(section, option)
+	if err != nil {
+		return "", err
+	}
+
+	top := l.layers[len(l.layers)-1]
+	top.rlock()
+	p := top.newInterpolator()
+	noInterpolate := top.options.NoInterpolate
+	top.runlock()
+	if noInterpolate {
+		return value, nil
+	}
+
+	p.getValue = l.getValue
+	p.hasSection = l.hasSection
+	return p.unfold(section, option, value)
+}
+
+// HasSection checks if any layer has the given section.
+func (l *Layered) HasSection(section string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return section == "" || section == DEFAULT_SECTION || l.hasSection(section)
+}
+
+// GetSectionList returns the sections of all the layers, in the order of
+// their first appearance from the lowest layer.
+func (l *Layered) GetSectionList() (sections []string) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	for _, c := range l.layers {
+		for _, section := range c.GetSectionList() {
+			if !seen[section] {
+				seen[section] = true
+				sections = append(sections, section)
+			}
+		}
+	}
+	return sections
+}
+
+// GetSectionKeyList returns the options of the section in all the layers,
+// in the order of their first appearance from the lowest layer.
+func (l *Layered) GetSectionKeyList(section string) (options []string) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	for _, c := range l.layers {
+		for _, option := range c.GetSectionKeyList(section) {
+			if !seen[option] {
+				seen[option] = true
+				options = append(options, option)
+			}
+		}
+	}
+	return options
+}
+
+// Flatten returns a new configuration with the layers merged in order, as
+// by MergeFrom.
+func (l *Layered) Flatten() *Config {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	c := New(nil)
+	for _, layer := range l.layers {
+		c.MergeFrom(layer)
+	}
+	return c
+}
+
+func (l *Layered) getValue(section, option string) (string, error) {
+	if section == "" {
+		section = DEFAULT_SECTION
+	}
+
+	for _, sec := range []string{section, DEFAULT_SECTION} {
+		for i := len(l.layers) - 1; i >= 0; i-- {
+			c := l.layers[i]
+			c.rlock()
+			tValue, ok := c.dataMap[sec][option]
+			if ok {
+				value := tValue.v
+				c.runlock()
+				return value, nil
+			}
+			c.runlock()
+		}
+	}
+
+	if l.hasSection(section) {
+		return "", &KeyError{Section: section, Key: option, Err: ErrKeyNotFound}
+	}
+	return "", &KeyError{Section: section, Key: option, Err: ErrSectionNotFound}
+}
+
+func (l *Layered) hasSection(section string) bool {
+	for _, c := range l.layers {
+		c.rlock()
+		_, ok := c.dataMap[section]
+		c.runlock()
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// index returns the index of the layer c, or -1.
+func (l *Layered) index(c *Config) int {
+	for i, layer := range l.layers {
+		if layer == c {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,88 @@
+// Copyright 2016 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestLayered(t *testing.T) {
+	system, err := LoadFromData([]byte(`root = /usr
+[server]
+host = localhost
+port = 80
+data = %(root)s/data
+[log]
+level = info
+`))
+	tAssertNil(t, err)
+	user, err := LoadFromData([]byte(`[server]
+port = 8080
+`))
+	tAssertNil(t, err)
+	project, err := LoadFromData([]byte(`root = /srv
+[cache]
+dir = ${server:data}/cache
+`))
+	tAssertNil(t, err)
+
+	l := NewLayered(system, user)
+	l.AddLayer(project)
+	tAssertEqual(t, []*Config{system, user, project}, l.Layers())
+
+	testLayered := func(section, option, expected string) {
+		t.Helper()
+		v, err := l.GetString(section, option)
+		tAssertNil(t, err)
+		tAssertEqual(t, expected, v)
+	}
+	testLayered("server", "host", "localhost")
+	testLayered("server", "port", "8080")
+	testLayered("server", "data", "/srv/data") // unfolded in the merged view
+	testLayered("cache", "dir", "/srv/data/cache")
+	testLayered("log", "root", "/srv")
+
+	v, err := l.GetValue("server", "data")
+	tAssertNil(t, err)
+	tAssertEqual(t, "%(root)s/data", v)
+	_, err = l.GetValue("server", "missing")
+	tAssertTrue(t, errors.Is(err, ErrKeyNotFound))
+	_, err = l.GetValue("missing", "missing")
+	tAssertTrue(t, errors.Is(err, ErrSectionNotFound))
+
+	tAssertEqual(t, []string{DEFAULT_SECTION, "server", "log", "cache"}, l.GetSectionList())
+	tAssertEqual(t, []string{"host", "port", "data"}, l.GetSectionKeyList("server"))
+	tAssertTrue(t, l.HasSection("cache"))
+
+	// Changes go to the write layer only
+	tAssertTrue(t, l.SetValue("server", "port", "9090"))
+	testLayered("server", "port", "9090")
+	tAssertEqual(t, "8080", user.MustValue("server", "port"))
+	tAssertTrue(t, l.SetWriteLayer(user))
+	tAssertFalse(t, l.SetValue("server", "port", "8081"))
+	testLayered("server", "port", "9090")
+	tAssertEqual(t, "8081", user.MustValue("server", "port"))
+	tAssertFalse(t, l.SetWriteLayer(New(nil)))
+
+	var buf bytes.Buffer
+	tAssertNil(t, user.WriteTo(&buf, ""))
+	tAssertEqual(t, "[server]\nport = 8081\n", buf.String())
+
+	tAssertTrue(t, l.RemoveLayer(project))
+	tAssertFalse(t, l.RemoveLayer(project))
+	testLayered("server", "port", "8081")
+	testLayered("server", "data", "/usr/data")
+	_, err = l.GetString("cache", "dir")
+	tAssertTrue(t, errors.Is(err, ErrSectionNotFound))
+
+	c := l.Flatten()
+	testGet(t, c, "server", "port", "8081")
+	testGet(t, c, "log", "level", "info")
+
+	_, err = NewLayered().GetString("server", "port")
+	tAssertTrue(t, errors.Is(err, ErrSectionNotFound))
+}
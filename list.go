@@ -0,0 +1,240 @@
+// Copyright 2016 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GetStrings has the same behaviour as GetString but splits the response by
+// delim. The elements are trimmed, and may be quoted with '"' to contain the
+// delimiter or spaces, with '"' and '\' escaped by a '\' inside the quotes:
+//
+//	hosts = a.example.com, "b, c.example.com", " d "
+//
+// An empty value is an empty list.
+func (c *Config) GetStrings(section, option, delim string) (values []string, err error) {
+	sv, err := c.GetString(section, option)
+	if err != nil || strings.TrimSpace(sv) == "" {
+		return nil, err
+	}
+	for _, s := range splitQuoted(sv, delim, -1) {
+		values = append(values, unquote(s))
+	}
+	return values, nil
+}
+
+// GetInts has the same behaviour as GetStrings but converts the elements to int.
+func (c *Config) GetInts(section, option, delim string) (values []int, err error) {
+	ss, err := c.GetStrings(section, option, delim)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range ss {
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// GetFloat64s has the same behaviour as GetStrings but converts the elements
+// to float.
+func (c *Config) GetFloat64s(section, option, delim string) (values []float64, err error) {
+	ss, err := c.GetStrings(section, option, delim)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range ss {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// GetBools has the same behaviour as GetStrings but converts the elements to
+// bool, see GetBool.
+func (c *Config) GetBools(section, option, delim string) (values []bool, err error) {
+	ss, err := c.GetStrings(section, option, delim)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range ss {
+		v, err := parseBool(s)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// GetDurations has the same behaviour as GetStrings but converts the elements
+// to time.Duration, as time.ParseDuration.
+func (c *Config) GetDurations(section, option, delim string) (values []time.Duration, err error) {
+	ss, err := c.GetStrings(section, option, delim)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range ss {
+		v, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// GetStringMap has the same behaviour as GetStrings but splits the elements
+// into keys and values at the first '=', as in "k1=v1, k2=v2". Keys and values
+// are trimmed and may be quoted. An element without '=' has an empty value.
+func (c *Config) GetStringMap(section, option, delim string) (values map[string]string, err error) {
+	sv, err := c.GetString(section, option)
+	if err != nil {
+		return nil, err
+	}
+	values = make(map[string]string)
+	if strings.TrimSpace(sv) == "" {
+		return values, nil
+	}
+	for _, s := range splitQuoted(sv, delim, -1) {
+		kv := splitQuoted(s, "=", 2)
+		if len(kv) == 1 {
+			kv = append(kv, "")
+		}
+		values[unquote(kv[0])] = unquote(kv[1])
+	}
+	return values, nil
+}
+
+// SetStrings adds the option with the values joined by delim, in the format
+// read by GetStrings. It returns the same as AddSectionKey.
+func (c *Config) SetStrings(section, option string, values []string, delim string) bool {
+	ss := make([]string, len(values))
+	for i, v := range values {
+		ss[i] = quote(v, delim, "")
+	}
+	return c.AddSectionKey(section, option, strings.Join(ss, delim))
+}
+
+// SetInts adds the option with the values joined by delim, see SetStrings.
+func (c *Config) SetInts(section, option string, values []int, delim string) bool {
+	ss := make([]string, len(values))
+	for i, v := range values {
+		ss[i] = strconv.Itoa(v)
+	}
+	return c.AddSectionKey(section, option, strings.Join(ss, delim))
+}
+
+// SetFloat64s adds the option with the values joined by delim, see SetStrings.
+func (c *Config) SetFloat64s(section, option string, values []float64, delim string) bool {
+	ss := make([]string, len(values))
+	for i, v := range values {
+		ss[i] = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return c.AddSectionKey(section, option, strings.Join(ss, delim))
+}
+
+// SetBools adds the option with the values joined by delim, see SetStrings.
+func (c *Config) SetBools(section, option string, values []bool, delim string) bool {
+	ss := make([]string, len(values))
+	for i, v := range values {
+		ss[i] = strconv.FormatBool(v)
+	}
+	return c.AddSectionKey(section, option, strings.Join(ss, delim))
+}
+
+// SetDurations adds the option with the values joined by delim, see SetStrings.
+func (c *Config) SetDurations(section, option string, values []time.Duration, delim string) bool {
+	ss := make([]string, len(values))
+	for i, v := range values {
+		ss[i] = v.String()
+	}
+	return c.AddSectionKey(section, option, strings.Join(ss, delim))
+}
+
+// SetStringMap adds the option with the key=value elements of values joined
+// by delim, sorted by key, in the format read by GetStringMap. It returns the
+// same as AddSectionKey.
+func (c *Config) SetStringMap(section, option string, values map[string]string, delim string) bool {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	ss := make([]string, len(keys))
+	for i, k := range keys {
+		ss[i] = quote(k, delim, "=") + "=" + quote(values[k], delim, "")
+	}
+	return c.AddSectionKey(section, option, strings.Join(ss, delim))
+}
+
+// splitQuoted splits s into at most n trimmed elements separated by sep,
+// or all elements if n < 0. The elements are not unquoted; a sep inside
+// quotes is ignored, see opensQuote.
+func splitQuoted(s, sep string, n int) []string {
+	var parts []string
+	start, quoted := 0, false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quoted && s[i] == '\\':
+			i++ // escaped character
+		case s[i] == '"' && (quoted || opensQuote(s[start:i])):
+			quoted = !quoted
+		case !quoted && sep != "" && strings.HasPrefix(s[i:], sep) && (n < 0 || len(parts) < n-1):
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + len(sep)
+			i += len(sep) - 1
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+// opensQuote reports whether a '"' after the text of an element starts a quoted
+// string, that is, if it starts the element or a value after a '='.
+func opensQuote(text string) bool {
+	text = strings.TrimSpace(text)
+	return text == "" || strings.HasSuffix(text, "=")
+}
+
+// unquote returns the element s without the quotes and the escapes, if it is
+// quoted.
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s)-1; i++ {
+		if s[i] == '\\' && i+1 < len(s)-1 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// quote quotes the element s if it could not be read back otherwise, that is,
+// if it is empty, has spaces around, contains delim, '"' or any of chars, or
+// could start a comment, see stripComments. The ';' and '#' of a quoted
+// element are escaped, so that they do not start a comment either.
+func quote(s, delim, chars string) string {
+	if s != "" && strings.TrimSpace(s) == s && !strings.Contains(s, delim) &&
+		!strings.ContainsAny(s, `"`+chars) && !strings.ContainsAny(s[:1], ";#") && stripComments(s) == s {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, ";", `\;`, "#", `\#`)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,136 @@
+// Copyright 2016 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ini
+
+// MergeMode is how the sections of the source are merged, see MergeOptions.
+type MergeMode int
+
+const (
+	MergeKeys       MergeMode = iota // the options of the source are added to the section
+	ReplaceSections                  // the section is replaced by the section of the source
+)
+
+// MergeOptions are the options of MergeWith.
+type MergeOptions struct {
+	Mode MergeMode // default is MergeKeys
+
+	// AppendValues appends the values of an option of the source to the
+	// values of the same option, see GetValues, instead of replacing them.
+	AppendValues bool
+}
+
+// tombstone is a deletion marker read with Options.Tombstones; the option is
+// empty for a section.
+type tombstone struct {
+	section string
+	option  string
+}
+
+// MergeWith merges the configuration source into p, as MergeFrom, with
+// the given options; nil is the default options.
+//
+// The deletion markers of the source, read with Options.Tombstones, are
+// applied first: "!key" removes the option of the section where it appears,
+// and the section header "[-section]" removes the section, which is then
+// replaced by the options which follow the header, if any:
+//
+//	[server]
+//	!debug
+//
+//	[-cache]
+func (p *Config) MergeWith(source *Config, opt *MergeOptions) {
+	if source == nil || source == p {
+		return
+	}
+	if opt == nil {
+		opt = &MergeOptions{}
+	}
+
+	source = source.copyOf()
+	p.lock()
+	defer p.unlock()
+
+	p.layer++
+	deleted := make(map[string]bool)
+	for _, t := range source.tombstones {
+		if t.option == "" {
+			p.removeSection(t.section)
+			deleted[t.section] = true
+		} else {
+			p.removeSectionKey(t.section, t.option)
+		}
+	}
+
+	for _, section := range source.sections {
+		options := source.optionListMap[section]
+		if deleted[section] && len(options) == 0 {
+			continue
+		}
+		if opt.Mode == ReplaceSections && len(options) > 0 {
+			for _, option := range p.sectionKeyList(section) {
+				p.removeSectionKey(section, option)
+			}
+		}
+
+		if p.addSection(section) {
+			pos := source.posMap[section]
+			p.posMap[section] = srcPos{pos.source, pos.line, p.layer}
+		}
+		for _, option := range options {
+			sv := source.dataMap[section][option]
+
+			var values []string
+			if tValue, ok := p.dataMap[section][option]; ok && opt.AppendValues {
+				values = append(valuesOf(tValue), valuesOf(sv)...)
+			} else if sv.values != nil {
+				values = valuesOf(sv)
+			}
+			p.addSectionKey(section, option, sv.v)
+
+			tValue := p.dataMap[section][option]
+			tValue.values = values
+			tValue.pos = srcPos{sv.pos.source, sv.pos.line, p.layer}
+			tValue.auto = sv.auto
+		}
+	}
+}
+
+// copyOf returns a copy of the sections, the options and the deletion
+// markers of c, taken under its lock, so that MergeWith and ApplyDefaults
+// never hold the locks of two configurations at once.
+func (c *Config) copyOf() *Config {
+	c.rlock()
+	defer c.runlock()
+
+	p := &Config{
+		sections:      append([]string(nil), c.sections...),
+		optionListMap: make(map[string][]string, len(c.optionListMap)),
+		dataMap:       make(map[string]map[string]*tValue, len(c.dataMap)),
+		posMap:        make(map[string]srcPos, len(c.posMap)),
+		tombstones:    append([]tombstone(nil), c.tombstones...),
+	}
+	for section, options := range c.optionListMap {
+		p.optionListMap[section] = append([]string(nil), options...)
+	}
+	for section, options := range c.dataMap {
+		values := make(map[string]*tValue, len(options))
+		for option, sv := range options {
+			values[option] = &tValue{v: sv.v, values: append([]string(nil), sv.values...), pos: sv.pos, auto: sv.auto}
+		}
+		p.dataMap[section] = values
+	}
+	for section, pos := range c.posMap {
+		p.posMap[section] = pos
+	}
+	return p
+}
+
+// valuesOf returns the values of the option, see GetValues.
+func valuesOf(tValue *tValue) []string {
+	if tValue.values != nil {
+		return append([]string(nil), tValue.values...)
+	}
+	return []string{tValue.v}
+}
@@ -4,11 +4,24 @@
 
 package ini
 
+import (
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"time"
+)
+
+// MustValue returns the value as GetValue, or the default value if there is
+// an error. Without a default value, it panics, or returns the zero value
+// with Options.Goconfig, as MustBool, MustInt, MustFloat64 and MustInt64.
 func (c *Config) MustValue(section, key string, defaultVal ...string) string {
 	v, err := c.GetValue(section, key)
 	if err != nil {
 		if len(defaultVal) > 0 {
 			return defaultVal[0]
+		} else if c.options.Goconfig {
+			return ""
 		} else {
 			panic(err)
 		}
@@ -21,6 +34,8 @@ func (c *Config) MustBool(section, key string, defaultVal ...bool) bool {
 	if err != nil {
 		if len(defaultVal) > 0 {
 			return defaultVal[0]
+		} else if c.options.Goconfig {
+			return false
 		} else {
 			panic(err)
 		}
@@ -33,6 +48,8 @@ func (c *Config) MustInt(section, key string, defaultVal ...int) int {
 	if err != nil {
 		if len(defaultVal) > 0 {
 			return defaultVal[0]
+		} else if c.options.Goconfig {
+			return 0
 		} else {
 			panic(err)
 		}
@@ -45,6 +62,8 @@ func (c *Config) MustFloat64(section, key string, defaultVal ...float64) float64
 	if err != nil {
 		if len(defaultVal) > 0 {
 			return defaultVal[0]
+		} else if c.options.Goconfig {
+			return 0
 		} else {
 			panic(err)
 		}
@@ -63,3 +82,224 @@ func (c *Config) MustString(section, key string, defaultVal ...string) string {
 	}
 	return v
 }
+
+// MustInt64 returns the value as GetInt64, or the default value if there is
+// an error. Without a default value, it panics, or returns the zero value
+// with Options.Goconfig.
+func (c *Config) MustInt64(section, key string, defaultVal ...int64) int64 {
+	v, err := c.GetInt64(section, key)
+	if err != nil {
+		if len(defaultVal) > 0 {
+			return defaultVal[0]
+		} else if c.options.Goconfig {
+			return 0
+		} else {
+			panic(err)
+		}
+	}
+	return v
+}
+
+func (c *Config) MustUint64(section, key string, defaultVal ...uint64) uint64 {
+	v, err := c.GetUint64(section, key)
+	if err != nil {
+		if len(defaultVal) > 0 {
+			return defaultVal[0]
+		} else {
+			panic(err)
+		}
+	}
+	return v
+}
+
+func (c *Config) MustDuration(section, key string, defaultVal ...time.Duration) time.Duration {
+	v, err := c.GetDuration(section, key)
+	if err != nil {
+		if len(defaultVal) > 0 {
+			return defaultVal[0]
+		} else {
+			panic(err)
+		}
+	}
+	return v
+}
+
+func (c *Config) MustByteSize(section, key string, defaultVal ...uint64) uint64 {
+	v, err := c.GetByteSize(section, key)
+	if err != nil {
+		if len(defaultVal) > 0 {
+			return defaultVal[0]
+		} else {
+			panic(err)
+		}
+	}
+	return v
+}
+
+func (c *Config) MustURL(section, key string, defaultVal ...*url.URL) *url.URL {
+	v, err := c.GetURL(section, key)
+	if err != nil {
+		if len(defaultVal) > 0 {
+			return defaultVal[0]
+		} else {
+			panic(err)
+		}
+	}
+	return v
+}
+
+func (c *Config) MustFileMode(section, key string, defaultVal ...os.FileMode) os.FileMode {
+	v, err := c.GetFileMode(section, key)
+	if err != nil {
+		if len(defaultVal) > 0 {
+			return defaultVal[0]
+		} else {
+			panic(err)
+		}
+	}
+	return v
+}
+
+func (c *Config) MustIP(section, key string, defaultVal ...net.IP) net.IP {
+	v, err := c.GetIP(section, key)
+	if err != nil {
+		if len(defaultVal) > 0 {
+			return defaultVal[0]
+		} else {
+			panic(err)
+		}
+	}
+	return v
+}
+
+func (c *Config) MustTime(section, key, layout string, defaultVal ...time.Time) time.Time {
+	v, err := c.GetTime(section, key, layout)
+	if err != nil {
+		if len(defaultVal) > 0 {
+			return defaultVal[0]
+		} else {
+			panic(err)
+		}
+	}
+	return v
+}
+
+func (c *Config) MustStrings(section, key, delim string, defaultVal ...[]string) []string {
+	v, err := c.GetStrings(section, key, delim)
+	if err != nil {
+		if len(defaultVal) > 0 {
+			return defaultVal[0]
+		} else {
+			panic(err)
+		}
+	}
+	return v
+}
+
+func (c *Config) MustInts(section, key, delim string, defaultVal ...[]int) []int {
+	v, err := c.GetInts(section, key, delim)
+	if err != nil {
+		if len(defaultVal) > 0 {
+			return defaultVal[0]
+		} else {
+			panic(err)
+		}
+	}
+	return v
+}
+
+func (c *Config) MustFloat64s(section, key, delim string, defaultVal ...[]float64) []float64 {
+	v, err := c.GetFloat64s(section, key, delim)
+	if err != nil {
+		if len(defaultVal) > 0 {
+			return defaultVal[0]
+		} else {
+			panic(err)
+		}
+	}
+	return v
+}
+
+func (c *Config) MustBools(section, key, delim string, defaultVal ...[]bool) []bool {
+	v, err := c.GetBools(section, key, delim)
+	if err != nil {
+		if len(defaultVal) > 0 {
+			return defaultVal[0]
+		} else {
+			panic(err)
+		}
+	}
+	return v
+}
+
+func (c *Config) MustDurations(section, key, delim string, defaultVal ...[]time.Duration) []time.Duration {
+	v, err := c.GetDurations(section, key, delim)
+	if err != nil {
+		if len(defaultVal) > 0 {
+			return defaultVal[0]
+		} else {
+			panic(err)
+		}
+	}
+	return v
+}
+
+func (c *Config) MustStringMap(section, key, delim string, defaultVal ...map[string]string) map[string]string {
+	v, err := c.GetStringMap(section, key, delim)
+	if err != nil {
+		if len(defaultVal) > 0 {
+			return defaultVal[0]
+		} else {
+			panic(err)
+		}
+	}
+	return v
+}
+
+func (c *Config) MustEnum(section, key string, candidates []string, defaultVal ...string) string {
+	v, err := c.GetEnum(section, key, candidates)
+	if err != nil {
+		if len(defaultVal) > 0 {
+			return defaultVal[0]
+		} else {
+			panic(err)
+		}
+	}
+	return v
+}
+
+func (c *Config) MustIntRange(section, key string, min, max int, defaultVal ...int) int {
+	v, err := c.GetIntRange(section, key, min, max)
+	if err != nil {
+		if len(defaultVal) > 0 {
+			return defaultVal[0]
+		} else {
+			panic(err)
+		}
+	}
+	return v
+}
+
+func (c *Config) MustFloat64Range(section, key string, min, max float64, defaultVal ...float64) float64 {
+	v, err := c.GetFloat64Range(section, key, min, max)
+	if err != nil {
+		if len(defaultVal) > 0 {
+			return defaultVal[0]
+		} else {
+			panic(err)
+		}
+	}
+	return v
+}
+
+func (c *Config) MustMatch(section, key string, re *regexp.Regexp, defaultVal ...string) string {
+	v, err := c.GetMatch(section, key, re)
+	if err != nil {
+		if len(defaultVal) > 0 {
+			return defaultVal[0]
+		} else {
+			panic(err)
+		}
+	}
+	return v
+}
@@ -0,0 +1,40 @@
+// Copyright 2016 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ini
+
+// Origin tells where the value of an option was set.
+type Origin struct {
+	Source string // file name, empty if read from an io.Reader or set by the API
+	Line   int    // 1-based line number, 0 if not read from a source
+
+	// Layer is the merge layer which last set the value: 0 for the first
+	// source, n for the source of the n-th MergeFrom, e.g. the n+1-th file
+	// of LoadConfigFile. A value set by the API has the current layer.
+	Layer int
+
+	Section   string // section of the value, which may be a parent or the DEFAULT section
+	Defaulted bool   // added by ApplyDefaults
+}
+
+// Origin returns where the value returned by GetValue for the option of the
+// section was set.
+//
+// It returns an error if either the section or the option do not exist.
+func (c *Config) Origin(section string, option string) (Origin, error) {
+	c.rlock()
+	defer c.runlock()
+
+	section, tValue, err := c.lookup(section, option)
+	if err != nil {
+		return Origin{}, err
+	}
+	return Origin{
+		Source:    tValue.pos.source,
+		Line:      tValue.pos.line,
+		Layer:     tValue.pos.layer,
+		Section:   section,
+		Defaulted: tValue.defaulted,
+	}, nil
+}
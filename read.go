@@ -6,28 +6,50 @@ package ini
 
 import (
 	"bufio"
-	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"unicode"
 )
 
+// Load reads the configuration from the file.
+//
+// With Options.Includes, the include directives read other files as if they
+// were in the file at the directive, with the same options: an include option
+// before the first section, an !include line anywhere, the path options of
+// an [include] section, and the path options of an [includeIf "condition"]
+// section if Options.IncludeIf(condition) is true:
+//
+//	include = common.ini
+//	!include conf.d/*.ini
+//
+//	[include]
+//	path = local.ini
+//
+// Relative paths are relative to the directory of the including file, or to
+// the working directory for LoadFrom, and may be glob patterns, see
+// filepath.Glob. An included option which is already set is a duplicate
+// option, see Options.DuplicateKeys. The included options keep their file and
+// line, see Origin, but are not written back. A file including itself,
+// directly or not, is an error matching ErrIncludeCycle; a nesting deeper
+// than Options.MaxIncludeDepth is an error too.
 func Load(fname string, opt *Options) (c *Config, err error) {
 	file, err := os.Open(fname)
 	if err != nil {
 		return nil, err
 	}
-
-	br := bufio.NewReader(file)
-	if bom, _ := br.Peek(3); string(bom) == "\xEF\xBB\xBF" {
-		br.Discard(3)
-	}
+	defer file.Close()
 
 	c = New(opt)
-	if err = c.read(br); err != nil {
+	if abs, err := filepath.Abs(fname); err == nil {
+		c.includes = []string{abs}
+	}
+	c.watch(fname, file)
+	if err = c.read(bufio.NewReader(file), fname); err != nil {
 		return nil, err
 	}
+	c.files = []string{fname}
 
 	if err = file.Close(); err != nil {
 		return nil, err
@@ -44,57 +66,317 @@ func LoadFrom(r io.Reader, opt *Options) (c *Config, err error) {
 		br = bufio.NewReader(r)
 	}
 
-	if bom, _ := br.Peek(3); string(bom) == "\xEF\xBB\xBF" {
-		br.Discard(3)
-	}
-
 	c = New(opt)
-	if err = c.read(br); err != nil {
+	if err = c.read(br, ""); err != nil {
 		return nil, err
 	}
 	return c, nil
 }
 
-func (c *Config) read(buf *bufio.Reader) (err error) {
+// read parses the configuration and keeps the source text of every line, so
+// that write can reproduce it. The source name is used in the errors.
+func (c *Config) read(buf *bufio.Reader, source string) (err error) {
+	if bom, _ := buf.Peek(3); string(bom) == "\xEF\xBB\xBF" {
+		buf.Discard(3)
+		c.bom = true
+	}
+
+	c.raw = true
+	c.eol = true
+	c.newline = ""
+	c.rawSectionMap[DEFAULT_SECTION] = []string{} // implicit header
+
 	var section, option string
-	var scanner = bufio.NewScanner(buf)
-	for scanner.Scan() {
-		l := strings.TrimRightFunc(stripComments(scanner.Text()), unicode.IsSpace)
+	var skipped bool      // working on an option skipped by DuplicateFirst
+	var comments []string // lines not yet attached to an option or a section
+	var header []string   // repeated section header not yet attached to an option
+	var after segment     // part of the source before the repeated header
+	last := segment{section: DEFAULT_SECTION}
+	var errs ParseErrors
+	for lineno := 1; ; lineno++ {
+		line, err := buf.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if line == "" {
+			break
+		}
+
+		text := strings.TrimSuffix(line, "\n")
+		if text == line {
+			c.eol = false
+		} else if strings.HasSuffix(text, "\r") {
+			text = strings.TrimSuffix(text, "\r")
+			if c.newline == "" {
+				c.newline = "\r\n"
+			}
+		} else if c.newline == "" {
+			c.newline = "\n"
+		}
+		if !c.eol {
+			line += "\n" // removed again by write
+		}
+
+		l := strings.TrimRightFunc(stripComments(text), unicode.IsSpace)
+		var include string // path of an include directive
 
 		// Switch written for readability (not performance)
 		switch {
 		// Empty line and comments
 		case len(l) == 0, l[0] == '#', l[0] == ';':
-			continue
+			comments = append(comments, line)
 
 		// New section. The [ must be at the start of the line
 		case l[0] == '[' && l[len(l)-1] == ']':
-			option = "" // reset multi-line value
-			section = strings.TrimSpace(l[1 : len(l)-1])
-			c.AddSection(section)
+			option, skipped = "", false // reset multi-line value
+			name, ok := c.parseSection(l[1 : len(l)-1])
+			if !ok {
+				e := newParseError(source, lineno, text, l, -1)
+				e.Reason = "invalid subsection"
+				e.Column = strings.IndexByte(text, '"') + 1
+				if !c.options.AllErrors {
+					return e
+				}
+				errs = append(errs, e)
+				break
+			}
+			if c.options.Tombstones && strings.HasPrefix(name, "-") {
+				name = strings.TrimSpace(name[1:])
+				c.tombstones = append(c.tombstones, tombstone{section: name})
+			}
+			section = name
+			if header != nil {
+				comments, header = append(header, comments...), nil
+			}
+			_, seen := c.rawSectionMap[section] // not seen if only included
+			first := c.addSection(section) || !seen
+			if section == DEFAULT_SECTION {
+				first = len(c.dataMap[section]) == 0 && len(c.rawSectionMap[section]) == 0 &&
+					last == segment{section: DEFAULT_SECTION}
+			}
+			if first {
+				c.rawSectionMap[section] = append(comments, line)
+				c.posMap[section] = srcPos{source: source, line: lineno}
+				last = segment{section: section}
+			} else {
+				// attached to the next option, see tValue.header
+				header, after = append(comments, line), last
+			}
+			comments = nil
+
+		// Continuation of a skipped multi-line value
+		case skipped && (l[0] == ' ' || l[0] == '\t'):
+			comments = append(comments, line)
 
 		// Continuation of multi-line value
 		// starts with whitespace, we're in a section and working on an option
 		case section != "" && option != "" && (l[0] == ' ' || l[0] == '\t'):
-			prev, _ := c.GetValue(section, option)
-			value := strings.TrimSpace(l)
-			c.AddSectionKey(section, option, prev+"\n"+value)
+			tValue := c.dataMap[section][option]
+			tValue.v += "\n" + strings.TrimSpace(l)
+			tValue.lines = append(append(tValue.lines, comments...), line)
+			tValue.rawv = tValue.v
+			if n := len(tValue.values); n > 0 {
+				tValue.values[n-1] = tValue.v
+			}
+			comments = nil
+
+		// Include directive
+		case c.options.Includes && (strings.HasPrefix(l, "!include ") || strings.HasPrefix(l, "!include\t")):
+			option, skipped = "", true
+			comments = append(comments, line)
+			include = strings.TrimSpace(l[len("!include"):])
+
+		// Deleted option
+		case c.options.Tombstones && l[0] == '!' && !strings.ContainsAny(l, "=:"):
+			option, skipped = "", false
+			sec := section
+			if sec == "" {
+				sec = DEFAULT_SECTION
+			}
+			c.addSection(sec)
+			c.tombstones = append(c.tombstones, tombstone{section: sec, option: strings.TrimSpace(l[1:])})
+			comments = append(comments, line)
 
 		// Other alternatives
 		default:
-			i := strings.IndexAny(l, "=:")
+			key, i := parseKey(l)
 
 			switch {
 			// Option and value
 			case i > 0 && l[0] != ' ' && l[0] != '\t': // found an =: and it's not a multiline continuation
-				option = strings.TrimSpace(l[0:i])
+				option, skipped = key, false
 				value := strings.TrimSpace(l[i+1:])
-				c.AddSectionKey(section, option, value)
+				quote := 0 // length of the quotes of a """value"""
+				if len(value) >= 6 && strings.HasPrefix(value, `"""`) && strings.HasSuffix(value, `"""`) {
+					value, quote = value[3:len(value)-3], 3
+				}
+
+				sec := section
+				if sec == "" {
+					sec = DEFAULT_SECTION
+				}
+				if directive, ok := c.isInclude(section, option); directive {
+					option, skipped = "", true
+					comments = append(comments, line)
+					if ok {
+						include = value
+					}
+					break
+				}
+				auto := option == "-"
+				if auto {
+					option = c.autoKey(sec)
+				}
+				var values []string
+				tValue, dup := c.dataMap[sec][option]
+				if dup {
+					switch c.options.DuplicateKeys {
+					case DuplicateFirst:
+						comments = append(comments, line)
+						option, skipped = "", true
+						continue
+					case DuplicateError:
+						e := newParseError(source, lineno, text, l, i)
+						e.Reason = "duplicate option"
+						e.Column = 1
+						if !c.options.AllErrors {
+							return e
+						}
+						errs = append(errs, e)
+						option, skipped = "", true
+						comments = append(comments, line)
+						continue
+					case DuplicateKeep:
+						values = tValue.values
+					}
+					// the option moves to its last line
+					to := c.removeDuplicate(sec, option)
+					if last == (segment{sec, option}) {
+						last = to
+					}
+					if after == (segment{sec, option}) {
+						after = to
+					}
+				}
+				c.addSectionKey(sec, option, value)
+
+				tValue = c.dataMap[sec][option]
+				tValue.auto = auto
+				if header != nil {
+					tValue.header, tValue.after = header, after
+					header, last = nil, segment{sec, option}
+				}
+				if c.options.DuplicateKeys == DuplicateKeep {
+					tValue.values = append(values, value)
+				}
+				tValue.comments = comments
+				tValue.lines = []string{line}
+				tValue.rawv = value
+				tValue.pos = srcPos{source: source, line: lineno}
+				tValue.prefix = line[:len(l)-quote-len(value)]
+				tValue.suffix = line[len(l)-quote:]
+				comments = nil
 
 			default:
-				return fmt.Errorf("ini: could not parse line: %v", l)
+				e := newParseError(source, lineno, text, l, i)
+				if !c.options.AllErrors {
+					return e
+				}
+				errs = append(errs, e)
 			}
 		}
+
+		if include != "" {
+			if err := c.include(source, include); err != nil {
+				e := includeErrors(source, lineno, text, l, err)
+				if !c.options.AllErrors {
+					return e[0]
+				}
+				errs = append(errs, e...)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	c.tail = append(header, comments...)
+	if c.newline == "" {
+		c.newline = "\n"
+	}
+	return nil
+}
+
+// removeDuplicate removes the option overwritten by a duplicate option, and
+// keeps its source lines where they were: before the next option of its part
+// of the section, or after the option or the header before it. It returns
+// the part of the section which replaces the one started by the option.
+func (c *Config) removeDuplicate(section, option string) (to segment) {
+	tValue := c.dataMap[section][option]
+	lines := append(append(tValue.comments, tValue.lines...), c.overwritten[segment{section, option}]...)
+	to = tValue.after
+
+	options := c.optionListMap[section]
+	switch {
+	case tValue.position+1 < len(options) && c.dataMap[section][options[tValue.position+1]].header == nil:
+		// removeSectionKey moves the header to the next option
+		next := c.dataMap[section][options[tValue.position+1]]
+		next.comments = append(lines, next.comments...)
+		to = segment{section, options[tValue.position+1]}
+	case tValue.header != nil:
+		end := c.segmentEnd(tValue.after)
+		c.overwritten[end] = append(append(c.overwritten[end], tValue.header...), lines...)
+	case tValue.position > 0:
+		prev := segment{section, options[tValue.position-1]}
+		c.overwritten[prev] = append(c.overwritten[prev], lines...)
+	default:
+		c.overwritten[segment{section: section}] = append(c.overwritten[segment{section: section}], lines...)
+	}
+
+	c.removeSectionKey(section, option)
+	return to
+}
+
+// segmentEnd returns the last option of the part of the section, or the
+// part itself if it has no options.
+func (c *Config) segmentEnd(seg segment) segment {
+	options := c.optionListMap[seg.section]
+	i := 0
+	if seg.option != "" {
+		i = c.dataMap[seg.section][seg.option].position
+	}
+	end := seg
+	for j, option := range options[i:] {
+		if (j > 0 || seg.option == "") && c.isSegment(seg.section, option) {
+			break
+		}
+		end.option = option
+	}
+	return end
+}
+
+// parseKey returns the option name of the line l and the index of the
+// separator after it. The name may be quoted with '"', '`' or '"""' to
+// contain separators, but not be empty; i is -1 if there is no separator.
+func parseKey(l string) (key string, i int) {
+	if strings.HasPrefix(l, `"""`) {
+		if j := strings.Index(l[3:], `"""`); j > 0 {
+			if k := strings.IndexAny(l[j+6:], "=:"); k >= 0 && strings.TrimSpace(l[j+6:j+6+k]) == "" {
+				return l[3 : j+3], j + 6 + k
+			}
+		}
+	}
+	if len(l) > 0 && (l[0] == '"' || l[0] == '`') {
+		if j := strings.IndexByte(l[1:], l[0]); j > 0 {
+			if k := strings.IndexAny(l[j+2:], "=:"); k >= 0 && strings.TrimSpace(l[j+2:j+2+k]) == "" {
+				return l[1 : j+1], j + 2 + k
+			}
+		}
+	}
+
+	i = strings.IndexAny(l, "=:")
+	if i < 0 {
+		return "", -1
 	}
-	return scanner.Err()
+	return strings.TrimSpace(l[0:i]), i
 }
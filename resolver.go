@@ -0,0 +1,83 @@
+// Copyright 2016 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// RegisterResolver registers the resolver of the ${scheme:ref} references,
+// which returns the value of ref. The ref is unfolded before it is passed to
+// the resolver, so it may contain references itself. A nil resolver removes
+// the scheme.
+//
+// The "env" scheme is registered by New: ${env:name} is the environment
+// variable, looked up by Options.LookupEnv. The "file" scheme is registered
+// only with Options.FileResolver: ${file:path} is the content of the file,
+// without the trailing newline. Resolvers take precedence over the
+// ${section:key} references. See CommandResolver for the "cmd" scheme, which
+// is not registered by default.
+func (c *Config) RegisterResolver(scheme string, fn func(ref string) (string, error)) {
+	if scheme == "" || strings.ContainsAny(scheme, ":{}") {
+		panic("invalid resolver scheme:" + scheme)
+	}
+
+	c.lock()
+	defer c.unlock()
+
+	if fn == nil {
+		delete(c.resolvers, scheme)
+		return
+	}
+	c.resolvers[scheme] = fn
+}
+
+// CommandResolver runs the command ref, split into fields, and returns its
+// output without the trailing newline. It is not registered by default,
+// as it lets the configuration run any command:
+//
+//	c.RegisterResolver("cmd", ini.CommandResolver)
+func CommandResolver(ref string) (string, error) {
+	args := strings.Fields(ref)
+	if len(args) == 0 {
+		return "", ErrUnresolvedReference
+	}
+	out, err := exec.Command(args[0], args[1:]...).Output()
+	if err != nil {
+		return "", err
+	}
+	return trimNewline(string(out)), nil
+}
+
+// resolveFile returns the content of the file name.
+func resolveFile(name string) (string, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	return trimNewline(string(data)), nil
+}
+
+// envResolver returns the resolver of the environment variables.
+func envResolver(lookupEnv func(key string) (string, bool)) func(name string) (string, error) {
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+	return func(name string) (string, error) {
+		v, ok := lookupEnv(name)
+		if !ok {
+			return "", ErrUnresolvedReference
+		}
+		return v, nil
+	}
+}
+
+// trimNewline removes a trailing "\n" or "\r\n" from s.
+func trimNewline(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	return strings.TrimSuffix(s, "\r")
+}
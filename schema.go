@@ -0,0 +1,387 @@
+// Copyright 2016 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema describes the expected sections and options of a configuration,
+// see Config.Validate and ParseSchema.
+type Schema struct {
+	Sections             []SectionSchema
+	AllowUnknownSections bool // sections which are not in Sections are allowed
+}
+
+// SectionSchema describes a section. An empty name is the DEFAULT section.
+type SectionSchema struct {
+	Name         string
+	Required     bool // the section has to exist
+	AllowUnknown bool // options which are not in Keys are allowed
+	Keys         []KeySchema
+}
+
+// KeySchema describes an option. The types are:
+//
+//	string    any value, the default
+//	bool      see GetBool
+//	int       see GetInt64
+//	uint      see GetUint64
+//	float     see GetFloat64
+//	duration  see GetDuration
+//	time      see GetTime, with Layout
+//	bytesize  see GetByteSize
+//	url       see GetURL
+//	filemode  see GetFileMode
+//	ip        see GetIP
+//
+// Min and Max are written as values of the type; they are not allowed for
+// the string, bool, url and ip types.
+type KeySchema struct {
+	Name     string
+	Type     string   // default is "string"
+	Required bool     // the option has to exist, in the section or the DEFAULT section
+	Default  string   // value of the option if it does not exist
+	Enum     []string // allowed values, see GetEnum
+	Min, Max string   // inclusive bounds, empty if unbounded
+	Pattern  string   // regexp the value has to match, see GetMatch
+	Layout   string   // layout of the time type, default is time.RFC3339
+}
+
+// schemaTypes converts the values of the types of KeySchema. The result is
+// the value as a number, to be compared with the bounds; ordered types only.
+var schemaTypes = map[string]func(s, layout string) (float64, error){
+	"string": func(s, layout string) (float64, error) { return 0, nil },
+	"bool": func(s, layout string) (float64, error) {
+		_, err := parseBool(s)
+		return 0, err
+	},
+	"int": func(s, layout string) (float64, error) {
+		v, err := strconv.ParseInt(s, intBase(s), 64)
+		return float64(v), err
+	},
+	"uint": func(s, layout string) (float64, error) {
+		v, err := strconv.ParseUint(s, intBase(s), 64)
+		return float64(v), err
+	},
+	"float": func(s, layout string) (float64, error) {
+		return strconv.ParseFloat(s, 64)
+	},
+	"duration": func(s, layout string) (float64, error) {
+		v, err := time.ParseDuration(s)
+		return float64(v), err
+	},
+	"time": func(s, layout string) (float64, error) {
+		v, err := time.Parse(layout, s)
+		return float64(v.UnixNano()), err
+	},
+	"bytesize": func(s, layout string) (float64, error) {
+		v, err := parseByteSize(s)
+		return float64(v), err
+	},
+	"url": func(s, layout string) (float64, error) {
+		_, err := parseURL(s)
+		return 0, err
+	},
+	"filemode": func(s, layout string) (float64, error) {
+		v, err := parseFileMode(s)
+		return float64(v), err
+	},
+	"ip": func(s, layout string) (float64, error) {
+		_, err := parseIP(s)
+		return 0, err
+	},
+}
+
+// unorderedTypes are the types of KeySchema without bounds.
+var unorderedTypes = map[string]bool{"string": true, "bool": true, "url": true, "ip": true}
+
+// keyCheck is a compiled KeySchema.
+type keyCheck struct {
+	*KeySchema
+	typ      string
+	parse    func(s, layout string) (float64, error)
+	min, max *float64
+	allowed  string // bounds, as in ValueError
+	layout   string
+	re       *regexp.Regexp
+}
+
+// compile checks the key schema.
+func (k *KeySchema) compile() (*keyCheck, error) {
+	kc := &keyCheck{KeySchema: k, typ: k.Type}
+	if kc.typ == "" {
+		kc.typ = "string"
+	}
+	if kc.layout = k.Layout; kc.layout == "" {
+		kc.layout = time.RFC3339
+	}
+	parse, ok := schemaTypes[kc.typ]
+	if !ok {
+		return nil, fmt.Errorf("ini: invalid schema: unknown type %q of option '%s'", k.Type, k.Name)
+	}
+	kc.parse = parse
+
+	if (k.Min != "" || k.Max != "") && unorderedTypes[kc.typ] {
+		return nil, fmt.Errorf("ini: invalid schema: bounds of unordered type %q of option '%s'", kc.typ, k.Name)
+	}
+	for _, b := range []struct {
+		s string
+		p **float64
+	}{{k.Min, &kc.min}, {k.Max, &kc.max}} {
+		if b.s == "" {
+			continue
+		}
+		n, err := parse(b.s, kc.layout)
+		if err != nil {
+			return nil, fmt.Errorf("ini: invalid schema: bound %q of option '%s': %w", b.s, k.Name, err)
+		}
+		*b.p = &n
+	}
+	switch {
+	case k.Min != "" && k.Max != "":
+		kc.allowed = "[" + k.Min + ", " + k.Max + "]"
+	case k.Min != "":
+		kc.allowed = ">= " + k.Min
+	case k.Max != "":
+		kc.allowed = "<= " + k.Max
+	}
+
+	if k.Pattern != "" {
+		re, err := regexp.Compile(k.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("ini: invalid schema: pattern of option '%s': %w", k.Name, err)
+		}
+		kc.re = re
+	}
+	return kc, nil
+}
+
+// check checks the value of the option.
+func (kc *keyCheck) check(c *Config, section, value string) error {
+	e := &ValueError{Section: section, Key: kc.Name, Value: value, Type: kc.typ}
+	s := strings.TrimSpace(value)
+
+	n, err := kc.parse(s, kc.layout)
+	if err != nil {
+		e.Err = err
+		return e
+	}
+
+	e.Err = ErrNotAllowed
+	if len(kc.Enum) > 0 {
+		found := false
+		for _, cand := range kc.Enum {
+			if s == cand || c.options.EnumIgnoreCase && strings.EqualFold(s, cand) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			e.Allowed = quoteList(kc.Enum)
+			return e
+		}
+	}
+	if kc.min != nil && n < *kc.min || kc.max != nil && n > *kc.max {
+		e.Allowed = kc.allowed
+		return e
+	}
+	if kc.re != nil && !kc.re.MatchString(s) {
+		e.Allowed = "matching " + strconv.Quote(kc.Pattern)
+		return e
+	}
+	return nil
+}
+
+// Validate checks the configuration against the schema and returns all the
+// problems found as a ValidationError: missing required sections and options,
+// unknown sections and options, values which are not of the type of the
+// option, and values which are not allowed by the constraints. Each
+// Violation has the position of the option or the section in the source.
+//
+// It returns another error if the schema is invalid.
+func (c *Config) Validate(schema *Schema) error {
+	var errs ValidationError
+	known := make(map[string]bool)
+	for _, ss := range schema.Sections {
+		section := ss.Name
+		if section == "" {
+			section = DEFAULT_SECTION
+		}
+		known[section] = true
+
+		checks := make(map[string]*keyCheck)
+		for i := range ss.Keys {
+			kc, err := ss.Keys[i].compile()
+			if err != nil {
+				return err
+			}
+			checks[kc.Name] = kc
+		}
+
+		if !c.HasSection(section) {
+			if ss.Required {
+				errs = append(errs, c.violation(section, "", ErrSectionNotFound))
+			}
+			continue
+		}
+
+		for i := range ss.Keys {
+			kc := checks[ss.Keys[i].Name]
+			value, err := c.GetString(section, kc.Name)
+			switch {
+			case errors.Is(err, ErrKeyNotFound) || errors.Is(err, ErrSectionNotFound):
+				if kc.Required {
+					errs = append(errs, c.violation(section, kc.Name, ErrKeyNotFound))
+				}
+			case err != nil:
+				errs = append(errs, c.violation(section, kc.Name, err))
+			default:
+				if err := kc.check(c, section, value); err != nil {
+					errs = append(errs, c.violation(section, kc.Name, err))
+				}
+			}
+		}
+
+		if !ss.AllowUnknown {
+			for _, option := range c.GetSectionKeyList(section) {
+				if checks[option] == nil {
+					errs = append(errs, c.violation(section, option, ErrUnknownKey))
+				}
+			}
+		}
+	}
+
+	if !schema.AllowUnknownSections {
+		for _, section := range c.GetSectionList() {
+			if !known[section] && (section != DEFAULT_SECTION || len(c.GetSectionKeyList(section)) > 0) {
+				errs = append(errs, c.violation(section, "", ErrUnknownSection))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// violation returns the violation of the option, or of the section if option
+// is empty, at its position in the source.
+func (c *Config) violation(section, option string, err error) *Violation {
+	c.rlock()
+	defer c.runlock()
+
+	pos := c.posMap[section]
+	if _, tValue, err := c.lookup(section, option); option != "" && err == nil {
+		pos = tValue.pos
+	}
+	return &Violation{Source: pos.source, Line: pos.line, Section: section, Key: option, Err: err}
+}
+
+// ParseSchema reads a schema from an INI file. Every option describes the
+// option of the same section, as a list of attributes separated by commas:
+// the type, then "required" and name=value attributes, with '|' separated
+// values of enum. Values containing commas may be quoted:
+//
+//	[server]
+//	@required = true
+//	host = string, required, pattern="^[a-z.]+$"
+//	port = int, min=1, max=65535, default=8080
+//	mode = string, enum=dev|prod
+//	timeout = duration, max=1m
+//	started = time, layout="2006-01-02 15:04"
+//
+// The options starting with '@' describe the section: @required and
+// @allow_unknown, see SectionSchema. @allow_unknown_sections may be set in the
+// DEFAULT section. The DEFAULT section is described if it has options other
+// than these.
+func ParseSchema(r io.Reader) (*Schema, error) {
+	p, err := LoadFrom(r, &Options{NoInterpolate: true})
+	if err != nil {
+		return nil, err
+	}
+
+	schema := new(Schema)
+	for _, section := range p.GetSectionList() {
+		ss := SectionSchema{Name: section}
+		for _, option := range p.GetSectionKeyList(section) {
+			value, _ := p.GetRawString(section, option)
+			if !strings.HasPrefix(option, "@") {
+				k, err := parseKeySchema(option, value)
+				if err != nil {
+					return nil, err
+				}
+				ss.Keys = append(ss.Keys, k)
+				continue
+			}
+
+			v, err := parseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("ini: invalid schema: %s in section '%s': %w", option, section, err)
+			}
+			switch {
+			case option == "@required":
+				ss.Required = v
+			case option == "@allow_unknown":
+				ss.AllowUnknown = v
+			case option == "@allow_unknown_sections" && section == DEFAULT_SECTION:
+				schema.AllowUnknownSections = v
+			default:
+				return nil, fmt.Errorf("ini: invalid schema: unknown %s in section '%s'", option, section)
+			}
+		}
+		if section != DEFAULT_SECTION || len(ss.Keys) > 0 || ss.Required || ss.AllowUnknown {
+			schema.Sections = append(schema.Sections, ss)
+		}
+	}
+	return schema, nil
+}
+
+// parseKeySchema parses the attributes of the option, see ParseSchema.
+func parseKeySchema(option, attrs string) (k KeySchema, err error) {
+	k.Name = option
+	for i, attr := range splitQuoted(attrs, ",", -1) {
+		kv := splitQuoted(attr, "=", 2)
+		name := kv[0]
+		if i == 0 && len(kv) == 1 {
+			k.Type = name
+			continue
+		}
+		if len(kv) == 1 {
+			if name != "required" {
+				return k, fmt.Errorf("ini: invalid schema: unknown attribute %q of option '%s'", name, option)
+			}
+			k.Required = true
+			continue
+		}
+
+		value := unquote(kv[1])
+		switch name {
+		case "default":
+			k.Default = value
+		case "enum":
+			k.Enum = strings.Split(value, "|")
+		case "min":
+			k.Min = value
+		case "max":
+			k.Max = value
+		case "pattern":
+			k.Pattern = value
+		case "layout":
+			k.Layout = value
+		default:
+			return k, fmt.Errorf("ini: invalid schema: unknown attribute %q of option '%s'", name, option)
+		}
+	}
+	_, err = k.compile()
+	return k, err
+}
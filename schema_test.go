@@ -0,0 +1,167 @@
+// Copyright 2016 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+const tSchema = `
+[DEFAULT]
+@allow_unknown_sections = false
+env = string, enum=dev|prod
+
+[server]
+@required = true
+host = string, required, pattern="^[a-z.]+$"
+port = int, min=1, max=65535, default=8080
+timeout = duration, max=1m
+started = time, layout="2006-01-02 15:04"
+cache = bytesize, min=1MiB
+
+[log]
+@allow_unknown = true
+level = string, enum=debug|info|error
+
+[database]
+@required = true
+`
+
+func TestValidate(t *testing.T) {
+	schema, err := ParseSchema(strings.NewReader(tSchema))
+	tAssertNil(t, err)
+	tAssertEqual(t, 4, len(schema.Sections))
+	tAssertEqual(t, DEFAULT_SECTION, schema.Sections[0].Name)
+	tAssertEqual(t, KeySchema{Name: "port", Type: "int", Min: "1", Max: "65535", Default: "8080"}, schema.Sections[1].Keys[1])
+	tAssertTrue(t, schema.Sections[1].Required)
+	tAssertTrue(t, schema.Sections[2].AllowUnknown)
+
+	c, err := LoadFromData([]byte(`env = prod
+[server]
+host = example.com
+port = 8080
+timeout = 30s
+started = 2023-01-02 15:04
+cache = 512MiB
+[log]
+level = info
+file = /var/log/app.log
+[database]
+`))
+	tAssertNil(t, err)
+	tAssertNil(t, c.Validate(schema))
+
+	data := `env = test
+[server]
+port = 70000
+timeout = 5m
+started = yesterday
+cache = 1kB
+extra = 1
+[log]
+level = trace
+[other]
+`
+	fname := "testdata/__schema.ini"
+	tAssertNil(t, os.WriteFile(fname, []byte(data), 0644))
+	defer os.Remove(fname)
+	c, err = Load(fname, nil)
+	tAssertNil(t, err)
+
+	err = c.Validate(schema)
+	var errs ValidationError
+	tAssertTrue(t, errors.As(err, &errs))
+
+	var got []string
+	for _, v := range errs {
+		got = append(got, v.Error())
+	}
+	tAssertEqual(t, []string{
+		`ini: testdata/__schema.ini:1: option 'env' in section 'DEFAULT' is not a valid string: "test" (allowed: "dev", "prod")`,
+		`ini: testdata/__schema.ini:2: required option 'host' not found in section 'server'`,
+		`ini: testdata/__schema.ini:3: option 'port' in section 'server' is not a valid int: "70000" (allowed: [1, 65535])`,
+		`ini: testdata/__schema.ini:4: option 'timeout' in section 'server' is not a valid duration: "5m" (allowed: <= 1m)`,
+		`ini: testdata/__schema.ini:5: option 'started' in section 'server' is not a valid time: "yesterday"`,
+		`ini: testdata/__schema.ini:6: option 'cache' in section 'server' is not a valid bytesize: "1kB" (allowed: >= 1MiB)`,
+		`ini: testdata/__schema.ini:7: unknown option 'extra' in section 'server'`,
+		`ini: testdata/__schema.ini:9: option 'level' in section 'log' is not a valid string: "trace" (allowed: "debug", "info", "error")`,
+		`ini: required section 'database' not found`,
+		`ini: testdata/__schema.ini:10: unknown section 'other'`,
+	}, got)
+
+	tAssertTrue(t, errors.Is(err, ErrUnknownKey))
+	tAssertTrue(t, errors.Is(err, ErrNotAllowed))
+	tAssertEqual(t, "port", errs[2].Key)
+	tAssertEqual(t, 3, errs[2].Line)
+	tAssertEqual(t, fname, errs[2].Source)
+	var ve *ValueError
+	tAssertTrue(t, errors.As(errs[4], &ve))
+
+	// without the multiple error Unwrap of Go 1.20
+	tAssertTrue(t, errs.Is(ErrUnknownKey))
+	tAssertFalse(t, errs.Is(ErrIncludeCycle))
+	ve = nil
+	tAssertTrue(t, errs.As(&ve))
+	tAssertEqual(t, "env", ve.Key)
+
+	_, err = ParseSchema(strings.NewReader("[s]\nk = number\n"))
+	tAssertNotNil(t, err)
+	err = c.Validate(&Schema{Sections: []SectionSchema{{Name: "server", Keys: []KeySchema{{Name: "host", Min: "a"}}}}})
+	tAssertNotNil(t, err)
+	tAssertFalse(t, errors.As(err, &errs))
+}
+
+func TestApplyDefaults(t *testing.T) {
+	schema, err := ParseSchema(strings.NewReader(`
+[DEFAULT]
+env = string, default=dev
+[server]
+host = string, default=localhost
+port = int, default=8080
+[cache]
+size = bytesize, default=64MiB
+`))
+	tAssertNil(t, err)
+
+	data := "port = 9000\n\n[server]\nhost = example.com\n"
+	for _, tt := range []struct {
+		mode DefaultsMode
+		want string
+	}{
+		{WriteDefaults, "port = 9000\nenv=dev\n\n[server]\nhost = example.com\n\n[cache]\nsize=64MiB\n"},
+		{OmitDefaults, data},
+		{CommentDefaults, "port = 9000\n# env=dev\n\n[server]\nhost = example.com\n\n[cache]\n# size=64MiB\n"},
+	} {
+		c, err := LoadFrom(strings.NewReader(data), &Options{Defaults: tt.mode})
+		tAssertNil(t, err)
+		c.ApplyDefaults(schema.Defaults())
+
+		testGet(t, c, "", "env", "dev")
+		testGet(t, c, "server", "host", "example.com")
+		testGet(t, c, "server", "port", "9000") // from DEFAULT, not overwritten
+		testGet(t, c, "cache", "size", "64MiB")
+		tAssertTrue(t, c.IsDefaulted("", "env"))
+		tAssertTrue(t, c.IsDefaulted("cache", "size"))
+		tAssertFalse(t, c.IsDefaulted("server", "host"))
+		tAssertFalse(t, c.HasSectionKey("server", "port"))
+
+		var buf bytes.Buffer
+		tAssertNil(t, c.WriteTo(&buf, ""))
+		tAssertEqual(t, tt.want, buf.String())
+	}
+
+	c, err := LoadFrom(strings.NewReader(data), &Options{Defaults: OmitDefaults})
+	tAssertNil(t, err)
+	c.ApplyDefaults(schema.Defaults())
+	c.AddSectionKey("cache", "size", "1GiB")
+	tAssertFalse(t, c.IsDefaulted("cache", "size"))
+	var buf bytes.Buffer
+	tAssertNil(t, c.WriteTo(&buf, ""))
+	tAssertEqual(t, data+"\n[cache]\nsize=1GiB\n", buf.String())
+}
@@ -4,9 +4,16 @@
 
 package ini
 
+import (
+	"strings"
+)
+
 // HasSection checks if the configuration has the given section.
 // (The default section always exists.)
 func (c *Config) HasSection(section string) bool {
+	c.rlock()
+	defer c.runlock()
+
 	if section == "" || section == DEFAULT_SECTION {
 		return true
 	}
@@ -22,6 +29,13 @@ func (c *Config) HasSection(section string) bool {
 // It returns true if the new section was inserted, and false if the section
 // already existed.
 func (c *Config) AddSection(section string) bool {
+	c.lock()
+	defer c.unlock()
+
+	return c.addSection(section)
+}
+
+func (c *Config) addSection(section string) bool {
 	if section == "" {
 		section = DEFAULT_SECTION
 	}
@@ -32,8 +46,8 @@ func (c *Config) AddSection(section string) bool {
 	c.dataMap[section] = make(map[string]*tValue)
 
 	// Section order
-	c.idSectionMap[section] = c.lastIdSection
-	c.lastIdSection++
+	c.idSectionMap[section] = len(c.sections)
+	c.sections = append(c.sections, section)
 
 	return true
 }
@@ -41,29 +55,134 @@ func (c *Config) AddSection(section string) bool {
 // RemoveSection removes a section from the configuration.
 // It returns true if the section was removed, and false if section did not exist.
 func (c *Config) RemoveSection(section string) bool {
+	c.lock()
+	defer c.unlock()
+
+	return c.removeSection(section)
+}
+
+func (c *Config) removeSection(section string) bool {
 	// Default section cannot be removed.
 	if section == "" || section == DEFAULT_SECTION {
 		return false
 	}
+	return c.deleteSection(section)
+}
+
+// deleteSection removes the section, which may be the DEFAULT section.
+func (c *Config) deleteSection(section string) bool {
 	if _, ok := c.dataMap[section]; !ok {
 		return false
 	}
 
+	i := c.idSectionMap[section]
+	c.sections = append(c.sections[:i], c.sections[i+1:]...)
+	for _, s := range c.sections[i:] {
+		c.idSectionMap[s]--
+	}
+
 	delete(c.dataMap, section)
-	delete(c.lastIdOptionMap, section)
+	delete(c.optionListMap, section)
+	delete(c.autoKeys, section)
 	delete(c.idSectionMap, section)
+	delete(c.rawSectionMap, section)
+	delete(c.posMap, section)
+	for seg := range c.overwritten {
+		if seg.section == section {
+			delete(c.overwritten, seg)
+		}
+	}
 	return true
 }
 
 // GetSectionList returns the list of sections in the configuration.
 // (The default section always exists).
-func (c *Config) GetSectionList() (sections []string) {
-	for i := 0; i < c.lastIdSection; i++ {
-		for section, id := range c.idSectionMap {
-			if id == i {
-				sections = append(sections, section)
-			}
+func (c *Config) GetSectionList() []string {
+	c.rlock()
+	defer c.runlock()
+
+	return append([]string{}, c.sections...)
+}
+
+// MoveSection moves the section before the section mark, or after it if
+// after is true.
+//
+// It returns false if either the section or mark do not exist.
+func (c *Config) MoveSection(section, mark string, after bool) bool {
+	c.lock()
+	defer c.unlock()
+
+	if section == "" {
+		section = DEFAULT_SECTION
+	}
+	if mark == "" {
+		mark = DEFAULT_SECTION
+	}
+	if _, ok := c.dataMap[section]; !ok {
+		return false
+	}
+	if _, ok := c.dataMap[mark]; !ok {
+		return false
+	}
+	if section == mark {
+		return true
+	}
+
+	sections := c.sections
+	i := c.idSectionMap[section]
+	sections = append(sections[:i], sections[i+1:]...)
+
+	i = 0
+	for sections[i] != mark {
+		i++
+	}
+	if after {
+		i++
+	}
+	sections = append(sections[:i], append([]string{section}, sections[i:]...)...)
+
+	for i, s := range sections {
+		c.idSectionMap[s] = i
+	}
+	c.sections = sections
+	return true
+}
+
+// ChildSections returns the direct child sections of the section, in order.
+// The name of a child section is the name of its parent, the separator set
+// by Options.SectionSeparator and the child name, e.g. "parent.child".
+func (c *Config) ChildSections(section string) (children []string) {
+	c.rlock()
+	defer c.runlock()
+
+	for _, s := range c.sections {
+		if s != section && c.parentSection(s) == section {
+			children = append(children, s)
+		}
+	}
+	return children
+}
+
+// ParentSection returns the name of the parent section of the section, which
+// does not need to exist, or "" if the section has no parent. See
+// ChildSections.
+//
+// The parent of a subsection, see SubsectionName, is the same subsection of
+// the parent section, e.g. `parent "sub"` for `parent.child "sub"`; the
+// separator is not looked up in the subsection.
+func (c *Config) ParentSection(section string) string {
+	return c.parentSection(section)
+}
+
+func (c *Config) parentSection(section string) string {
+	if name, subsection, ok := SplitSubsection(section); ok {
+		if parent := c.parentSection(name); parent != "" {
+			return SubsectionName(parent, subsection)
 		}
+		return ""
+	}
+	if i := strings.LastIndex(section, c.options.SectionSeparator); i > 0 {
+		return section[:i]
 	}
-	return
+	return ""
 }
@@ -4,9 +4,16 @@
 
 package ini
 
+import (
+	"strconv"
+)
+
 // HasSectionKey checks if the configuration has the given option in the section.
 // It returns false if either the option or section do not exist.
 func (c *Config) HasSectionKey(section string, option string) bool {
+	c.rlock()
+	defer c.runlock()
+
 	if section == "" {
 		section = DEFAULT_SECTION
 	}
@@ -25,24 +32,53 @@ func (c *Config) HasSectionKey(section string, option string) bool {
 // it is created in advance.
 //
 // It returns true if the option and value were inserted, and false if the value
-// was overwritten.
+// was overwritten. An overwritten option keeps its position.
+//
+// The "-" option is numbered automatically, as "#1", "#2", etc., and written
+// back as "-".
 func (c *Config) AddSectionKey(section string, option string, value string) bool {
+	c.lock()
+	defer c.unlock()
+
+	return c.addSectionKey(section, option, value)
+}
+
+func (c *Config) addSectionKey(section string, option string, value string) bool {
 	if section == "" {
 		section = DEFAULT_SECTION
 	}
 
-	c.AddSection(section) // Make sure section exists
+	c.addSection(section) // Make sure section exists
 
-	_, ok := c.dataMap[section][option]
-	c.dataMap[section][option] = &tValue{c.lastIdOptionMap[section], value}
-	c.lastIdOptionMap[section]++
-	return !ok
+	auto := option == "-"
+	if auto {
+		option = c.autoKey(section)
+	}
+	if tValue, ok := c.dataMap[section][option]; ok {
+		tValue.v = value
+		tValue.values = nil
+		tValue.defaulted = false
+		tValue.included = false
+		tValue.pos = srcPos{layer: c.layer}
+		return false
+	}
+
+	c.dataMap[section][option] = &tValue{position: len(c.optionListMap[section]), v: value, pos: srcPos{layer: c.layer}, auto: auto}
+	c.optionListMap[section] = append(c.optionListMap[section], option)
+	return true
 }
 
 // RemoveSectionKey removes a option and value from the configuration.
 // It returns true if the option and value were removed, and false otherwise,
 // including if the section did not exist.
 func (c *Config) RemoveSectionKey(section string, option string) bool {
+	c.lock()
+	defer c.unlock()
+
+	return c.removeSectionKey(section, option)
+}
+
+func (c *Config) removeSectionKey(section string, option string) bool {
 	if section == "" {
 		section = DEFAULT_SECTION
 	}
@@ -51,27 +87,171 @@ func (c *Config) RemoveSectionKey(section string, option string) bool {
 		return false
 	}
 
-	_, ok := c.dataMap[section][option]
+	tValue, ok := c.dataMap[section][option]
+	if !ok {
+		return false
+	}
+
+	options := c.optionListMap[section]
+	if tValue.header != nil {
+		c.removeSegment(section, option, tValue)
+	}
+	options = append(options[:tValue.position], options[tValue.position+1:]...)
+	for _, s := range options[tValue.position:] {
+		c.dataMap[section][s].position--
+	}
+	c.optionListMap[section] = options
+
 	delete(c.dataMap[section], option)
-	return ok
+	delete(c.overwritten, segment{section, option})
+	return true
+}
+
+// removeSegment moves the repeated header of the option, which is removed,
+// to the next option of its part of the section, see tValue.header. The
+// header is dropped if the part has no other option.
+func (c *Config) removeSegment(section, option string, tValue *tValue) {
+	from, to := segment{section, option}, tValue.after
+	if options := c.optionListMap[section]; tValue.position+1 < len(options) {
+		next := options[tValue.position+1]
+		if nValue := c.dataMap[section][next]; nValue.header == nil {
+			nValue.header, nValue.after = tValue.header, tValue.after
+			to = segment{section, next}
+		}
+	}
+
+	for _, values := range c.dataMap {
+		for _, v := range values {
+			if v.header != nil && v.after == from {
+				v.after = to
+			}
+		}
+	}
 }
 
 // GetSectionKeyList returns only the list of options available in the given section.
 func (c *Config) GetSectionKeyList(section string) (options []string) {
+	c.rlock()
+	defer c.runlock()
+
+	return c.sectionKeyList(section)
+}
+
+func (c *Config) sectionKeyList(section string) (options []string) {
 	if section == "" {
 		section = DEFAULT_SECTION
 	}
-	if _, ok := c.dataMap[section]; !ok {
-		return
+	return append(options, c.optionListMap[section]...)
+}
+
+// InsertKeyBefore adds a new option and value to the configuration, before
+// the option mark of the section. If the option exists, its value is
+// overwritten and it is moved before mark.
+//
+// It returns false, and the configuration is not changed, if mark does not
+// exist in the section.
+func (c *Config) InsertKeyBefore(section, option, value, mark string) bool {
+	c.lock()
+	defer c.unlock()
+
+	return c.insertKey(section, option, value, mark, false)
+}
+
+// InsertKeyAfter is the same as InsertKeyBefore, but adds the option after
+// the option mark.
+func (c *Config) InsertKeyAfter(section, option, value, mark string) bool {
+	c.lock()
+	defer c.unlock()
+
+	return c.insertKey(section, option, value, mark, true)
+}
+
+// MoveKey moves the option of the section before the option mark, or after
+// it if after is true. Comments before the option are moved with it.
+//
+// It returns false if either the option or mark do not exist in the section.
+func (c *Config) MoveKey(section, option, mark string, after bool) bool {
+	c.lock()
+	defer c.unlock()
+
+	if section == "" {
+		section = DEFAULT_SECTION
 	}
+	return c.moveKey(section, option, mark, after)
+}
 
-	for i := 0; i < c.lastIdOptionMap[section]; i++ {
-		for s, tValue := range c.dataMap[section] {
-			if tValue.position == i {
-				options = append(options, s)
-				break
-			}
+func (c *Config) insertKey(section, option, value, mark string, after bool) bool {
+	if section == "" {
+		section = DEFAULT_SECTION
+	}
+	if _, ok := c.dataMap[section][mark]; !ok {
+		return false
+	}
+
+	c.addSectionKey(section, option, value)
+	return c.moveKey(section, option, mark, after)
+}
+
+func (c *Config) moveKey(section, option, mark string, after bool) bool {
+	tValue, ok := c.dataMap[section][option]
+	if !ok {
+		return false
+	}
+	if _, ok := c.dataMap[section][mark]; !ok {
+		return false
+	}
+	if option == mark {
+		return true
+	}
+
+	options := c.optionListMap[section]
+	options = append(options[:tValue.position], options[tValue.position+1:]...)
+
+	i := 0
+	for options[i] != mark {
+		i++
+	}
+	if after {
+		i++
+	}
+	options = append(options[:i], append([]string{option}, options[i:]...)...)
+
+	for i, s := range options {
+		c.dataMap[section][s].position = i
+	}
+	c.optionListMap[section] = options
+	return true
+}
+
+// GetValues gets the (raw) string values for the given option in the section.
+// An option which was read more than once with Options.DuplicateKeys set to
+// DuplicateKeep has all its values, in order; any other option has one value.
+//
+// It returns nil if either the section or the option do not exist.
+func (c *Config) GetValues(section string, option string) []string {
+	c.rlock()
+	defer c.runlock()
+
+	if section == "" {
+		section = DEFAULT_SECTION
+	}
+	if tValue, ok := c.dataMap[section][option]; ok && tValue.values != nil {
+		return append([]string(nil), tValue.values...)
+	}
+	value, err := c.getValue(section, option)
+	if err != nil {
+		return nil
+	}
+	return []string{value}
+}
+
+// autoKey returns the next "#n" name of the "-" option of the section.
+func (c *Config) autoKey(section string) string {
+	for {
+		c.autoKeys[section]++
+		option := "#" + strconv.Itoa(c.autoKeys[section])
+		if _, ok := c.dataMap[section][option]; !ok {
+			return option
 		}
 	}
-	return
 }
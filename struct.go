@@ -0,0 +1,307 @@
+// Copyright 2016 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Section is a section of a configuration.
+type Section struct {
+	c    *Config
+	name string
+}
+
+// Section returns the given section of the configuration, which does not
+// need to exist yet. An empty name is the DEFAULT section.
+func (c *Config) Section(name string) *Section {
+	if name == "" {
+		name = DEFAULT_SECTION
+	}
+	return &Section{c: c, name: name}
+}
+
+// Name returns the name of the section.
+func (s *Section) Name() string {
+	return s.name
+}
+
+// MapTo fills the exported fields of the struct pointed to by v with the
+// options of the configuration.
+//
+// Fields are mapped to the options of the DEFAULT section; struct fields are
+// mapped to sections, see Section.MapTo. The name of the option or the section
+// is the field name, or the name given by the `ini` tag:
+//
+//	type Config struct {
+//		Name   string `ini:"name"`
+//		Port   int    `ini:"port,default=8080"`
+//		Server struct {
+//			Host string `ini:"host,default=localhost"`
+//		} `ini:"server"`
+//		Ignored string `ini:"-"`
+//	}
+//
+// The default value is used if the option does not exist; it has to be the
+// last tag option and may contain commas. Fields of embedded structs without
+// a tag name are mapped to the same section as the fields of the outer struct.
+// Values are unfolded as by GetString and converted as by GetBool, GetInt and
+// GetFloat64. Fields of missing options without default are left unchanged.
+func (c *Config) MapTo(v interface{}) error {
+	rv, err := structValue(v)
+	if err != nil {
+		return err
+	}
+	return c.mapTo(DEFAULT_SECTION, "", rv)
+}
+
+// MapTo fills the exported fields of the struct pointed to by v with the
+// options of the section. Struct fields are mapped to the sections named by
+// the section name and the field name joined by ".", e.g. "parent.child".
+// See Config.MapTo for the tags.
+func (s *Section) MapTo(v interface{}) error {
+	rv, err := structValue(v)
+	if err != nil {
+		return err
+	}
+	return s.c.mapTo(s.name, s.name+".", rv)
+}
+
+// ReflectFrom adds the exported fields of the struct pointed to by v to the
+// configuration. It is the reverse of MapTo; fields with the omitempty tag
+// option are not added if they have the zero value:
+//
+//	Comment string `ini:"comment,omitempty"`
+func (c *Config) ReflectFrom(v interface{}) error {
+	rv, err := structValue(v)
+	if err != nil {
+		return err
+	}
+	return c.reflectFrom(DEFAULT_SECTION, "", rv)
+}
+
+// ReflectFrom adds the exported fields of the struct pointed to by v to the
+// section. It is the reverse of Section.MapTo.
+func (s *Section) ReflectFrom(v interface{}) error {
+	rv, err := structValue(v)
+	if err != nil {
+		return err
+	}
+	return s.c.reflectFrom(s.name, s.name+".", rv)
+}
+
+// mapTo fills the struct rv from the section; the sections of struct fields
+// are named prefix+name.
+func (c *Config) mapTo(section, prefix string, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag, ok := parseFieldTag(field)
+		if !ok {
+			continue
+		}
+		fv := rv.Field(i)
+
+		if sv, ok := structField(fv); ok {
+			if field.Anonymous && tag.name == "" {
+				err := c.mapTo(section, prefix, sv)
+				if err != nil {
+					return err
+				}
+			} else if err := c.mapTo(prefix+tag.key, prefix+tag.key+".", sv); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, err := c.GetString(section, tag.key)
+		if err != nil {
+			if !errors.Is(err, ErrKeyNotFound) && !errors.Is(err, ErrSectionNotFound) {
+				return err
+			}
+			if !tag.hasDefault {
+				continue
+			}
+			value = tag.defaultValue
+		}
+		if err := setField(fv, value); err != nil {
+			return fmt.Errorf("ini: could not map option '%s' in section '%s' to field %s: %w",
+				tag.key, section, field.Name, err,
+			)
+		}
+	}
+	return nil
+}
+
+// reflectFrom adds the struct rv to the section; the sections of struct fields
+// are named prefix+name.
+func (c *Config) reflectFrom(section, prefix string, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag, ok := parseFieldTag(field)
+		if !ok {
+			continue
+		}
+		fv := rv.Field(i)
+
+		if fv.Kind() == reflect.Ptr && fv.IsNil() {
+			continue
+		}
+		if sv, ok := structField(fv); ok {
+			if field.Anonymous && tag.name == "" {
+				err := c.reflectFrom(section, prefix, sv)
+				if err != nil {
+					return err
+				}
+			} else if err := c.reflectFrom(prefix+tag.key, prefix+tag.key+".", sv); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if tag.omitEmpty && fv.IsZero() {
+			continue
+		}
+		value, err := formatField(fv)
+		if err != nil {
+			return fmt.Errorf("ini: could not reflect field %s to option '%s' in section '%s': %w",
+				field.Name, tag.key, section, err,
+			)
+		}
+		c.AddSectionKey(section, tag.key, value)
+	}
+	return nil
+}
+
+// structValue returns the struct pointed to by v.
+func structValue(v interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("ini: non-nil pointer to struct expected, got %T", v)
+	}
+	return rv.Elem(), nil
+}
+
+// structField returns the struct of a struct field or a pointer to struct
+// field, allocating the struct if the pointer is nil.
+func structField(fv reflect.Value) (reflect.Value, bool) {
+	switch {
+	case fv.Kind() == reflect.Struct:
+		return fv, true
+	case fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Struct:
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		return fv.Elem(), true
+	}
+	return reflect.Value{}, false
+}
+
+// fieldTag is the parsed `ini` tag of a struct field.
+type fieldTag struct {
+	name         string // name given by the tag
+	key          string // name of the option or section
+	omitEmpty    bool
+	hasDefault   bool
+	defaultValue string
+}
+
+// parseFieldTag parses the tag of the field; it returns false if the field
+// is not mapped.
+func parseFieldTag(field reflect.StructField) (tag fieldTag, ok bool) {
+	if field.PkgPath != "" && !(field.Anonymous && field.Type.Kind() == reflect.Struct) { // unexported
+		return tag, false
+	}
+
+	s := field.Tag.Get("ini")
+	if s == "-" {
+		return tag, false
+	}
+
+	i := strings.Index(s, ",")
+	if i < 0 {
+		i = len(s)
+	}
+	tag.name, s = strings.TrimSpace(s[:i]), s[i:]
+	for s != "" {
+		s = s[1:] // skip ','
+		if strings.HasPrefix(s, "default=") {
+			tag.hasDefault, tag.defaultValue = true, s[len("default="):]
+			break
+		}
+
+		i := strings.Index(s, ",")
+		if i < 0 {
+			i = len(s)
+		}
+		if strings.TrimSpace(s[:i]) == "omitempty" {
+			tag.omitEmpty = true
+		}
+		s = s[i:]
+	}
+
+	tag.key = tag.name
+	if tag.key == "" {
+		tag.key = field.Name
+	}
+	return tag, true
+}
+
+// setField converts value to the type of the field and sets it.
+func setField(fv reflect.Value, value string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		v, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(value, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(v)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+// formatField converts the field to string.
+func formatField(fv reflect.Value) (string, error) {
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(fv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(fv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(fv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(fv.Float(), 'g', -1, fv.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", fv.Type())
+}
@@ -0,0 +1,110 @@
+// Copyright 2016 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type tPerson struct {
+	Name     string  `ini:"name"`
+	Relation string  `ini:"relation"`
+	Age      int     `ini:"age"`
+	Money    float32 `ini:"money"`
+	Height   uint16  `ini:"height,default=180"`
+
+	Child *struct {
+		Age     int8 `ini:"age"`
+		Married bool `ini:"married"`
+	} `ini:"child"`
+}
+
+type tBase struct {
+	Google string `ini:"google"`
+}
+
+type tConf struct {
+	tBase
+	Search string `ini:"search"`
+	Lang   string `ini:"lang,default=en,zh"`
+	Skip   string `ini:"-"`
+	skip   string
+
+	Demo struct {
+		Key1  string `ini:"key1"`
+		Array string `ini:"array_key"`
+	}
+	Parent tPerson `ini:"parent"`
+}
+
+func TestMapTo(t *testing.T) {
+	c, err := Load("testdata/conf.ini", nil)
+	tAssertNil(t, err)
+
+	var conf = tConf{Skip: "skip", skip: "skip"}
+	tAssertNil(t, c.MapTo(&conf))
+
+	tAssertEqual(t, "www.google.com", conf.Google)
+	tAssertEqual(t, "http://www.google.com", conf.Search)
+	tAssertEqual(t, "en,zh", conf.Lang)
+	tAssertEqual(t, "skip", conf.Skip)
+	tAssertEqual(t, "skip", conf.skip)
+	tAssertEqual(t, "Let's us goconfig!!!", conf.Demo.Key1)
+	tAssertEqual(t, "1,2,3,4,5", conf.Demo.Array)
+	tAssertEqual(t, "john", conf.Parent.Name)
+	tAssertEqual(t, 32, conf.Parent.Age)
+	tAssertEqual(t, float32(1.25), conf.Parent.Money)
+	tAssertEqual(t, uint16(180), conf.Parent.Height)
+	tAssertEqual(t, int8(3), conf.Parent.Child.Age)
+	tAssertEqual(t, true, conf.Parent.Child.Married)
+
+	var parent tPerson
+	tAssertNil(t, c.Section("parent").MapTo(&parent))
+	tAssertEqual(t, conf.Parent, parent)
+
+	c.AddSectionKey("parent.child", "age", "three")
+	err = c.Section("parent").MapTo(&parent)
+	tAssertNotNil(t, err)
+	tAssertTrue(t, strings.Contains(err.Error(), "'age' in section 'parent.child'"), err)
+
+	c.AddSectionKey("parent", "name", "%(404)s")
+	err = c.Section("parent").MapTo(&parent)
+	tAssertTrue(t, errors.Is(err, ErrUnresolvedReference), err)
+
+	tAssertNotNil(t, c.MapTo(conf))
+	tAssertNotNil(t, c.MapTo((*tConf)(nil)))
+}
+
+func TestReflectFrom(t *testing.T) {
+	var conf tConf
+	conf.Google = "www.google.com"
+	conf.Parent.Name = "john"
+	conf.Parent.Money = 1.25
+	conf.Parent.Height = 170
+
+	c := New(nil)
+	tAssertNil(t, c.ReflectFrom(&conf))
+	tAssertEqual(t, []string{"DEFAULT", "Demo", "parent"}, c.GetSectionList())
+	tAssertEqual(t, []string{"google", "search", "lang"}, c.GetSectionKeyList(""))
+	tAssertEqual(t, "1.25", c.MustValue("parent", "money"))
+	tAssertEqual(t, "0", c.MustValue("parent", "age"))
+
+	var conf2 tConf
+	tAssertNil(t, c.MapTo(&conf2))
+	tAssertEqual(t, conf.Parent.Height, conf2.Parent.Height)
+	tAssertEqual(t, conf.Parent.Money, conf2.Parent.Money)
+	tAssertEqual(t, "", conf2.Lang)
+
+	var opt struct {
+		Name    string `ini:"name,omitempty"`
+		Comment string `ini:"comment,omitempty"`
+	}
+	opt.Name = "x"
+	c = New(nil)
+	tAssertNil(t, c.Section("s").ReflectFrom(&opt))
+	tAssertEqual(t, []string{"name"}, c.GetSectionKeyList("s"))
+}
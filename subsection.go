@@ -0,0 +1,85 @@
+// Copyright 2016 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"strings"
+)
+
+// SubsectionName returns the name of the subsection of the section, as read
+// from a [section "subsection"] header when Options.Subsections is set:
+//
+//	SubsectionName("remote", "origin") // remote "origin"
+//
+// The subsection is quoted with the '"' and '\' characters escaped by a '\',
+// so the name can be written back as a header.
+func SubsectionName(section, subsection string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return section + ` "` + r.Replace(subsection) + `"`
+}
+
+// SplitSubsection splits the name of a subsection, as returned by
+// SubsectionName, into the section and the unquoted subsection. It returns
+// false if name is not the name of a subsection.
+func SplitSubsection(name string) (section, subsection string, ok bool) {
+	i := strings.IndexAny(name, " \t")
+	if i <= 0 {
+		return "", "", false
+	}
+	section = name[:i]
+	s := strings.TrimLeft(name[i:], " \t")
+	if len(s) < 2 || s[0] != '"' {
+		return "", "", false
+	}
+
+	var b strings.Builder
+	for j := 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			if j++; j == len(s) {
+				return "", "", false
+			}
+			b.WriteByte(s[j])
+		case '"':
+			if j != len(s)-1 {
+				return "", "", false
+			}
+			return section, b.String(), true
+		default:
+			b.WriteByte(s[j])
+		}
+	}
+	return "", "", false // missing '"'
+}
+
+// GetSubsectionList returns the unquoted subsections of the section, in order.
+// See Options.Subsections.
+func (c *Config) GetSubsectionList(section string) (subsections []string) {
+	c.rlock()
+	defer c.runlock()
+
+	for _, name := range c.sections {
+		if s, sub, ok := SplitSubsection(name); ok && s == section {
+			subsections = append(subsections, sub)
+		}
+	}
+	return subsections
+}
+
+// parseSection returns the name of the section of the header text between
+// the brackets. With Options.Subsections, the subsection of a
+// [section "subsection"] header is unquoted and quoted again by
+// SubsectionName; it returns false if the quotes are invalid.
+func (c *Config) parseSection(header string) (string, bool) {
+	header = strings.TrimSpace(header)
+	if !c.options.Subsections || !strings.ContainsAny(header, `"`) {
+		return header, true
+	}
+	section, subsection, ok := SplitSubsection(header)
+	if !ok {
+		return "", false
+	}
+	return SubsectionName(section, subsection), true
+}
@@ -5,29 +5,71 @@
 package ini
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"net"
+	"net/url"
 	"os"
-	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // GetValue gets the (raw) string value for the given option in the section.
 // The raw string value is not subjected to unfolding, which was illustrated in
 // the beginning of this documentation.
 //
+// With Options.Inherit, an option which is not in the section is looked up
+// in the parent sections, see ParentSection, before the DEFAULT section.
+//
+// With Options.Goconfig, the value is unfolded as GetString.
+//
 // It returns an error if either the section or the option do not exist.
 func (c *Config) GetValue(section string, option string) (value string, err error) {
+	c.rlock()
+	defer c.runlock()
+
+	if c.options.Goconfig {
+		return c.getString(section, option)
+	}
+	return c.getValue(section, option)
+}
+
+func (c *Config) getValue(section string, option string) (value string, err error) {
+	_, tValue, err := c.lookup(section, option)
+	if err != nil {
+		return "", err
+	}
+	return tValue.v, nil
+}
+
+// lookup returns the value of the option as GetValue, and the section where
+// it was found: the section, a parent section with Options.Inherit, or the
+// DEFAULT section.
+func (c *Config) lookup(section string, option string) (string, *tValue, error) {
 	if section == "" {
 		section = DEFAULT_SECTION
 	}
 
-	if _, ok := c.dataMap[section]; ok {
-		if tValue, ok := c.dataMap[section][option]; ok {
-			return tValue.v, nil
+	if tValue, ok := c.dataMap[section][option]; ok {
+		return section, tValue, nil
+	}
+	if c.options.Inherit {
+		for s := c.parentSection(section); s != ""; s = c.parentSection(s) {
+			if tValue, ok := c.dataMap[s][option]; ok {
+				return s, tValue, nil
+			}
 		}
 	}
-	return c.GetDefaultValue(option)
+	if tValue, ok := c.dataMap[DEFAULT_SECTION][option]; ok {
+		return DEFAULT_SECTION, tValue, nil
+	}
+
+	if _, ok := c.dataMap[section]; ok {
+		return "", nil, &KeyError{Section: section, Key: option, Err: ErrKeyNotFound}
+	}
+	return "", nil, &KeyError{Section: section, Key: option, Err: ErrSectionNotFound}
 }
 
 // GetDefaultValue gets the (raw) string value for the given option from the
@@ -35,10 +77,17 @@ func (c *Config) GetValue(section string, option string) (value string, err erro
 //
 // It returns an error if the option does not exist in the DEFAULT section.
 func (c *Config) GetDefaultValue(option string) (value string, err error) {
+	c.rlock()
+	defer c.runlock()
+
+	return c.getDefaultValue(option)
+}
+
+func (c *Config) getDefaultValue(option string) (value string, err error) {
 	if tValue, ok := c.dataMap[DEFAULT_SECTION][option]; ok {
 		return tValue.v, nil
 	}
-	return "", fmt.Errorf("ini: option '%s' not found", option)
+	return "", &KeyError{Section: DEFAULT_SECTION, Key: option, Err: ErrKeyNotFound}
 }
 
 // GetBool has the same behaviour as String but converts the response to bool.
@@ -49,15 +98,19 @@ func (c *Config) GetBool(section string, option string) (value bool, err error)
 	}
 
 	sv, err := c.GetString(section, option)
-	if err != nil {
-		return false, err
+	if err == nil {
+		value, err = parseBool(sv)
 	}
 
-	value, ok := boolString[strings.ToLower(sv)]
+	return value, err
+}
+
+// parseBool converts s to bool, see "boolString".
+func parseBool(s string) (bool, error) {
+	value, ok := boolString[strings.ToLower(s)]
 	if !ok {
-		return false, fmt.Errorf("ini: could not parse bool value: %v", sv)
+		return false, fmt.Errorf("ini: could not parse bool value: %v", s)
 	}
-
 	return value, nil
 }
 
@@ -89,80 +142,278 @@ func (c *Config) GetFloat64(section string, option string) (value float64, err e
 	return value, err
 }
 
-// GetString gets the string value for the given option in the section.
-// If the value needs to be unfolded (see e.g. %(host)s example in the beginning
-// of this documentation), then String does this unfolding automatically, up to
-// _DEPTH_VALUES number of iterations.
+// GetInt64 has the same behaviour as String but converts the response to
+// int64. The value is decimal, or hexadecimal, octal or binary with the
+// prefix "0x", "0o" or "0b"; "017" is decimal.
 //
-// It returns an error if either the section or the option do not exist, or the
-// unfolding cycled.
-func (c *Config) GetString(section string, option string) (value string, err error) {
+// It returns a ValueError if the value is not an int64.
+func (c *Config) GetInt64(section string, option string) (value int64, err error) {
+	err = c.getTyped(section, option, "int64", func(s string) (err error) {
+		value, err = strconv.ParseInt(s, intBase(s), 64)
+		return err
+	})
+	return value, err
+}
+
+// GetUint64 has the same behaviour as GetInt64 but converts the response to
+// uint64.
+func (c *Config) GetUint64(section string, option string) (value uint64, err error) {
+	err = c.getTyped(section, option, "uint64", func(s string) (err error) {
+		value, err = strconv.ParseUint(s, intBase(s), 64)
+		return err
+	})
+	return value, err
+}
+
+// GetDuration has the same behaviour as String but converts the response to
+// time.Duration, as time.ParseDuration, e.g. "30s" or "1h30m".
+func (c *Config) GetDuration(section string, option string) (value time.Duration, err error) {
+	err = c.getTyped(section, option, "duration", func(s string) (err error) {
+		value, err = time.ParseDuration(s)
+		return err
+	})
+	return value, err
+}
+
+// GetTime has the same behaviour as String but converts the response to
+// time.Time, as time.Parse with the layout, e.g. time.RFC3339.
+func (c *Config) GetTime(section string, option string, layout string) (value time.Time, err error) {
+	err = c.getTyped(section, option, "time", func(s string) (err error) {
+		value, err = time.Parse(layout, s)
+		return err
+	})
+	return value, err
+}
+
+// GetByteSize has the same behaviour as String but converts the response to
+// a number of bytes. The number may be followed by a unit: B, the decimal
+// units kB, MB, GB, TB, PB and EB, or the binary units KiB, MiB, GiB, TiB, PiB
+// and EiB. The units are case insensitive, and may be written without the B,
+// as in "512M" or "1.5Gi".
+func (c *Config) GetByteSize(section string, option string) (value uint64, err error) {
+	err = c.getTyped(section, option, "byte size", func(s string) (err error) {
+		value, err = parseByteSize(s)
+		return err
+	})
+	return value, err
+}
+
+// GetURL has the same behaviour as String but converts the response to an URL,
+// as url.Parse. The URL has to be absolute.
+func (c *Config) GetURL(section string, option string) (value *url.URL, err error) {
+	err = c.getTyped(section, option, "URL", func(s string) (err error) {
+		value, err = parseURL(s)
+		return err
+	})
+	return value, err
+}
+
+// GetFileMode has the same behaviour as String but converts the response to
+// os.FileMode. The value is an octal number, as in "0755", "755" or "0o755".
+func (c *Config) GetFileMode(section string, option string) (value os.FileMode, err error) {
+	err = c.getTyped(section, option, "file mode", func(s string) error {
+		v, err := parseFileMode(s)
+		value = fileMode(v)
+		return err
+	})
+	return value, err
+}
+
+// GetIP has the same behaviour as String but converts the response to net.IP,
+// as net.ParseIP.
+func (c *Config) GetIP(section string, option string) (value net.IP, err error) {
+	err = c.getTyped(section, option, "IP address", func(s string) (err error) {
+		value, err = parseIP(s)
+		return err
+	})
+	return value, err
+}
+
+// getTyped gets the string value and converts it by parse; the conversion
+// error is returned as a ValueError for the type typ.
+func (c *Config) getTyped(section string, option string, typ string, parse func(s string) error) error {
 	if section == "" {
 		section = DEFAULT_SECTION
 	}
 
-	value, err = c.GetValue(section, option)
+	sv, err := c.GetString(section, option)
 	if err != nil {
-		return "", err
+		return err
 	}
+	if err = parse(strings.TrimSpace(sv)); err != nil {
+		return &ValueError{Section: section, Key: option, Value: sv, Type: typ, Err: err}
+	}
+	return nil
+}
 
-	// % variables
-	computedVal, err := c.computeVar(&value, varRegExp, 2, 2, func(varName *string) string {
-		lowerVar := *varName
-		// search variable in default section as well as current section
-		varVal, _ := c.dataMap[DEFAULT_SECTION][lowerVar]
-		if _, ok := c.dataMap[section][lowerVar]; ok {
-			varVal = c.dataMap[section][lowerVar]
-		}
-		return varVal.v
-	})
-	value = *computedVal
+// intBase returns the base for strconv of the integer s, 0 if s has a base
+// prefix and 10 otherwise.
+func intBase(s string) int {
+	s = strings.TrimLeft(s, "+-")
+	if len(s) > 2 && s[0] == '0' && strings.ContainsAny(s[1:2], "xXoObB") {
+		return 0
+	}
+	return 10
+}
 
-	if err != nil {
-		return value, err
+// parseURL converts s to an absolute URL.
+func parseURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err == nil && !u.IsAbs() {
+		return nil, errors.New("missing scheme")
 	}
+	return u, err
+}
 
-	// $ environment variables
-	computedVal, err = c.computeVar(&value, envVarRegExp, 2, 1, func(varName *string) string {
-		return os.Getenv(*varName)
-	})
-	value = *computedVal
-	return value, err
+// parseFileMode converts the octal s to permission bits, see GetFileMode.
+func parseFileMode(s string) (uint64, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0o"), "0O")
+	v, err := strconv.ParseUint(s, 8, 32)
+	if err == nil && v > 07777 {
+		err = strconv.ErrRange
+	}
+	return v, err
 }
 
-// Substitutes values, calculated by callback, on matching regex
-func (c *Config) computeVar(
-	beforeValue *string, regx *regexp.Regexp, headsz, tailsz int,
-	withVar func(*string) string,
-) (*string, error) {
-	var i int
-	computedVal := beforeValue
-	for i = 0; i < _DEPTH_VALUES; i++ { // keep a sane depth
-		vr := regx.FindStringSubmatchIndex(*computedVal)
-		if len(vr) == 0 {
-			break
-		}
+// parseIP converts s to an IP address.
+func parseIP(s string) (net.IP, error) {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, errors.New("invalid IP address")
+	}
+	return ip, nil
+}
+
+// fileMode converts the octal permission bits v, with the setuid, setgid and
+// sticky bits, to os.FileMode.
+func fileMode(v uint64) os.FileMode {
+	mode := os.FileMode(v) & os.ModePerm
+	if v&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if v&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if v&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
+
+// byteUnits are the multipliers of the units of byte sizes, without the "b".
+var byteUnits = map[string]uint64{
+	"":   1,
+	"k":  1e3,
+	"m":  1e6,
+	"g":  1e9,
+	"t":  1e12,
+	"p":  1e15,
+	"e":  1e18,
+	"ki": 1 << 10,
+	"mi": 1 << 20,
+	"gi": 1 << 30,
+	"ti": 1 << 40,
+	"pi": 1 << 50,
+	"ei": 1 << 60,
+}
+
+// parseByteSize converts s to a number of bytes, see GetByteSize.
+func parseByteSize(s string) (uint64, error) {
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	num, unit := s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+	if unit != "b" {
+		unit = strings.TrimSuffix(unit, "b")
+	} else {
+		unit = ""
+	}
+	mul, ok := byteUnits[unit]
+	if !ok || num == "" {
+		return 0, errors.New("invalid byte size")
+	}
 
-		varname := (*computedVal)[vr[headsz]:vr[headsz+1]]
-		varVal := withVar(&varname)
-		if varVal == "" {
-			return &varVal, fmt.Errorf("ini: Option not found: %s", varname)
+	if !strings.Contains(num, ".") {
+		v, err := strconv.ParseUint(num, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		if v > math.MaxUint64/mul {
+			return 0, strconv.ErrRange
 		}
+		return v * mul, nil
+	}
+
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, err
+	}
+	if f *= float64(mul); f >= math.MaxUint64 {
+		return 0, strconv.ErrRange
+	}
+	return uint64(f), nil
+}
+
+// GetString gets the string value for the given option in the section.
+// If the value needs to be unfolded (see e.g. %(host)s example in the beginning
+// of this documentation), then String does this unfolding automatically, up to
+// _DEPTH_VALUES levels of references.
+//
+// A %(var)s reference is the option var of the section or of the DEFAULT
+// section. If there is no such option, the reference may name an option of
+// another section as %(section.key)s, where the separator is set by
+// Options.RefSeparator. A ${envvar} reference is an environment variable, and
+// ${section:key} is an option of another section. As in the shell,
+// ${envvar-word} and ${envvar=word} are word if envvar is unset,
+// ${envvar?word} fails with the message word if envvar is unset, and
+// ${envvar+word} is word if envvar is set, else empty; with a ':' before the
+// operator, as in ${envvar:-word}, an empty variable is handled as unset.
+// Options.LookupEnv sets the environment lookup. A ${scheme:ref} reference is
+// resolved by the resolver of the scheme, see RegisterResolver.
+//
+// $$ and \$ are a literal $, and %% is a literal %, so $${HOME} and %%(name)s
+// are unfolded to the literal ${HOME} and %(name)s.
+//
+// Options.NoSectionRefs disables the references to other sections. The
+// references in the value of an option of another section are resolved in
+// that section.
+//
+// Options.NoInterpolate disables the unfolding, GetString then returns the
+// raw value as GetRawString.
+//
+// It returns an error if either the section or the option do not exist, or the
+// unfolding failed, see InterpolationError.
+func (c *Config) GetString(section string, option string) (value string, err error) {
+	c.rlock()
+	defer c.runlock()
 
-		// substitute by new value and take off leading '%(' and trailing ')s'
-		//  %(foo)s => headsz=2, tailsz=2
-		//  ${foo}  => headsz=2, tailsz=1
-		newVal := (*computedVal)[0:vr[headsz]-headsz] + varVal + (*computedVal)[vr[headsz+1]+tailsz:]
-		computedVal = &newVal
+	return c.getString(section, option)
+}
+
+func (c *Config) getString(section string, option string) (value string, err error) {
+	if section == "" {
+		section = DEFAULT_SECTION
 	}
 
-	if i >= _DEPTH_VALUES {
-		retVal := ""
-		return &retVal, fmt.Errorf(
-			"ini: Possible cycle while unfolding variables: max depth of %d reached",
-			_DEPTH_VALUES,
-		)
+	value, err = c.getValue(section, option)
+	if err != nil {
+		return "", err
 	}
+	if c.options.NoInterpolate {
+		return value, nil
+	}
+	return c.newInterpolator().unfold(section, option, value)
+}
+
+// GetRawString gets the string value for the given option in the section,
+// as GetString, but never unfolds it: references and escapes such as %(var)s,
+// ${envvar} and %% are returned as written.
+//
+// It returns an error if either the section or the option do not exist.
+func (c *Config) GetRawString(section string, option string) (value string, err error) {
+	c.rlock()
+	defer c.runlock()
 
-	return computedVal, nil
+	return c.getValue(section, option)
 }
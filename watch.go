@@ -0,0 +1,169 @@
+// Copyright 2016 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+)
+
+// Change lists the options of a section which were added, removed or
+// modified by a reload.
+type Change struct {
+	Section  string
+	Added    []string
+	Removed  []string
+	Modified []string
+}
+
+// Diff lists the changes of a reload, one per changed section.
+type Diff []Change
+
+// OnChange registers a callback which is called with the changes after the
+// configuration was reloaded by Reload, ReloadData or Watch.
+func (c *Config) OnChange(fn func(diff Diff)) {
+	c.lock()
+	defer c.unlock()
+
+	c.onChange = append(c.onChange, fn)
+}
+
+// OnError registers a callback which is called when Watch fails to reload
+// the configuration. The configuration is left untouched in that case.
+func (c *Config) OnError(fn func(err error)) {
+	c.lock()
+	defer c.unlock()
+
+	c.onError = append(c.onError, fn)
+}
+
+// Watch polls the files the configuration was loaded from, and the files
+// they included, every interval, and reloads the configuration when a file
+// changed since it was loaded, see Reload. It returns when ctx is done.
+//
+// The new contents are swapped in at once, under the lock of the Config:
+// Watch returns an error if the Config is not in BlockMode.
+func (c *Config) Watch(ctx context.Context, interval time.Duration) error {
+	if !c.BlockMode {
+		return errors.New("ini: Watch needs a Config in BlockMode, see Options.BlockMode")
+	}
+
+	c.rlock()
+	loaded, files, last := len(c.files), c.watched, c.stats
+	c.runlock()
+
+	if loaded == 0 {
+		return errors.New("ini: configuration loaded from in-memory data, nothing to watch")
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		stats := statFiles(files)
+		if stats.equal(last) {
+			continue
+		}
+		last = stats
+
+		if err := c.Reload(); err != nil {
+			c.rlock()
+			onError := c.onError
+			c.runlock()
+
+			for _, fn := range onError {
+				fn(err)
+			}
+			continue
+		}
+
+		// the reloaded files may include other files
+		c.rlock()
+		files, last = c.watched, c.stats
+		c.runlock()
+	}
+}
+
+// watch records the state of the file, which is about to be read, see Watch.
+func (c *Config) watch(fname string, file *os.File) {
+	var stat fileStat
+	if fi, err := file.Stat(); err == nil {
+		stat = fileStat{exists: true, size: fi.Size(), modTime: fi.ModTime()}
+	}
+	c.watched = append(c.watched, fname)
+	c.stats = append(c.stats, stat)
+}
+
+// fileStats holds the state of the watched files.
+type fileStats []fileStat
+
+type fileStat struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+func statFiles(files []string) fileStats {
+	stats := make(fileStats, len(files))
+	for i, fname := range files {
+		if fi, err := os.Stat(fname); err == nil {
+			stats[i] = fileStat{exists: true, size: fi.Size(), modTime: fi.ModTime()}
+		}
+	}
+	return stats
+}
+
+func (p fileStats) equal(q fileStats) bool {
+	if len(p) != len(q) {
+		return false
+	}
+	for i := range p {
+		if p[i].exists != q[i].exists || p[i].size != q[i].size || !p[i].modTime.Equal(q[i].modTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// diff returns the changes from c to p.
+func (c *Config) diff(p *Config) (diff Diff) {
+	for _, section := range p.sections {
+		change := Change{Section: section}
+		for _, option := range p.optionListMap[section] {
+			if tValue, ok := c.dataMap[section][option]; !ok {
+				change.Added = append(change.Added, option)
+			} else if tValue.v != p.dataMap[section][option].v {
+				change.Modified = append(change.Modified, option)
+			}
+		}
+		for _, option := range c.optionListMap[section] {
+			if _, ok := p.dataMap[section][option]; !ok {
+				change.Removed = append(change.Removed, option)
+			}
+		}
+		if len(change.Added) > 0 || len(change.Removed) > 0 || len(change.Modified) > 0 {
+			diff = append(diff, change)
+		}
+	}
+
+	for _, section := range c.sections {
+		if _, ok := p.dataMap[section]; !ok {
+			if options := c.sectionKeyList(section); len(options) > 0 {
+				diff = append(diff, Change{Section: section, Removed: options})
+			}
+		}
+	}
+	return diff
+}
@@ -0,0 +1,145 @@
+// Copyright 2016 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "watch.ini")
+	modTime := time.Now().Add(-time.Hour)
+	writeFile := func(data string) {
+		if err := os.WriteFile(fname, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		modTime = modTime.Add(time.Second)
+		if err := os.Chtimes(fname, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeFile("a = 1\n[s]\nb = 2\nc = 3\n[old]\nx = 1\n")
+	c, err := Load(fname, &Options{BlockMode: true})
+	tAssertNil(t, err)
+
+	diffs := make(chan Diff, 1)
+	errs := make(chan error, 1)
+	c.OnChange(func(diff Diff) { diffs <- diff })
+	c.OnError(func(err error) { errs <- err })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- c.Watch(ctx, time.Millisecond) }()
+
+	writeFile("a = 1\n[s]\nb = 20\nd = 4\n[new]\ny = 1\n")
+	select {
+	case diff := <-diffs:
+		tAssertEqual(t, Diff{
+			{Section: "s", Added: []string{"d"}, Removed: []string{"c"}, Modified: []string{"b"}},
+			{Section: "new", Added: []string{"y"}},
+			{Section: "old", Removed: []string{"x"}},
+		}, diff)
+	case err := <-errs:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+	tAssertEqual(t, 20, c.MustInt("s", "b"))
+
+	writeFile("a = 1\n[s]\nbad line\n")
+	select {
+	case diff := <-diffs:
+		t.Fatal(diff)
+	case err := <-errs:
+		var pe *ParseError
+		tAssertTrue(t, errors.As(err, &pe))
+		tAssertEqual(t, 3, pe.Line)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+	tAssertEqual(t, 20, c.MustInt("s", "b"))
+
+	cancel()
+	tAssertEqual(t, context.Canceled, <-done)
+
+	c, err = LoadFrom(strings.NewReader("a = 1"), &Options{BlockMode: true})
+	tAssertNil(t, err)
+	tAssertNotNil(t, c.Watch(context.Background(), time.Millisecond))
+
+	writeFile("a = 1\n")
+	c, err = Load(fname, nil)
+	tAssertNil(t, err)
+	err = c.Watch(context.Background(), time.Millisecond)
+	tAssertNotNil(t, err)
+	tAssertTrue(t, strings.Contains(err.Error(), "BlockMode"))
+}
+
+func TestWatchIncludes(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Now().Add(-time.Hour)
+	writeFile := func(name, data string) string {
+		fname := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(fname), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fname, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		modTime = modTime.Add(time.Second)
+		if err := os.Chtimes(fname, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+		return fname
+	}
+
+	a := writeFile("conf.d/a.ini", "[a]\nkey = 1\n")
+	b := writeFile("conf.d/b.ini", "[b]\nkey = 1\n")
+	main := writeFile("main.ini", "!include conf.d/*.ini\n[main]\nkey = 1\n")
+	c, err := Load(main, &Options{BlockMode: true, Includes: true})
+	tAssertNil(t, err)
+	tAssertEqual(t, []string{main, a, b}, c.watched)
+
+	diffs := make(chan Diff, 1)
+	errs := make(chan error, 1)
+	c.OnChange(func(diff Diff) { diffs <- diff })
+	c.OnError(func(err error) { errs <- err })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Watch(ctx, time.Millisecond)
+
+	// the included files are watched, and still watched after a reload
+	for _, section := range []string{"b", "a"} {
+		writeFile("conf.d/"+section+".ini", "["+section+"]\nkey = 2\n")
+		select {
+		case diff := <-diffs:
+			tAssertEqual(t, Diff{{Section: section, Modified: []string{"key"}}}, diff)
+		case err := <-errs:
+			t.Fatal(err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+		}
+	}
+}
+
+func TestReloadOnChange(t *testing.T) {
+	c, err := LoadFromData([]byte("a = 1\n"))
+	tAssertNil(t, err)
+
+	var diffs []Diff
+	c.OnChange(func(diff Diff) { diffs = append(diffs, diff) })
+
+	tAssertNil(t, c.ReloadData(strings.NewReader("a = 1\n")))
+	tAssertEqual(t, 0, len(diffs))
+	tAssertNil(t, c.ReloadData(strings.NewReader("a = 2\n")))
+	tAssertEqual(t, []Diff{{{Section: DEFAULT_SECTION, Modified: []string{"a"}}}}, diffs)
+}
@@ -6,7 +6,7 @@ package ini
 
 import (
 	"bufio"
-	"fmt"
+	"bytes"
 	"io"
 	"os"
 	"strings"
@@ -56,43 +56,193 @@ func (c *Config) WriteFile(fname string, perm os.FileMode, header string) error
 	return file.Close()
 }
 
+// write writes the configuration. The source text of sections and options
+// which were read and not changed since is written back unchanged.
 func (c *Config) write(buf *bufio.Writer, header string) (err error) {
+	c.rlock()
+	defer c.runlock()
+
+	var b bytes.Buffer
+
+	if c.bom {
+		b.WriteString("\xEF\xBB\xBF")
+	}
+
 	if header != "" {
 		header = strings.Replace(header, "\r\n", "\n", -1)
-		header = strings.Replace(header, "\n", "\r\n", -1)
+		header = strings.Replace(header, "\n", c.newline, -1)
 
 		// Add comment character after of each new line.
 		if i := strings.Index(header, "\n"); i != -1 {
 			header = strings.Replace(header, "\n", "\n"+c.comment, -1)
 		}
 
-		if _, err = buf.WriteString(c.comment + header + "\n"); err != nil {
-			return err
+		b.WriteString(c.comment + header + c.newline)
+	}
+
+	next := c.segments()
+	for _, section := range c.sections {
+		options := c.optionListMap[section]
+		if c.omitSection(section) {
+			continue
+		}
+
+		// The implicit header of the DEFAULT section is only valid first.
+		lines, ok := c.rawSectionMap[section]
+		switch {
+		case ok && (hasHeader(lines) || section == c.sections[0]):
+			writeLines(&b, lines)
+		case section == DEFAULT_SECTION && len(options) == 0 && len(lines) == 0:
+			// Skip default section if empty.
+		default:
+			writeLines(&b, lines)
+			b.WriteString(c.newline + "[" + section + "]" + c.newline)
 		}
+
+		c.writeSegment(&b, segment{section: section}, next)
+	}
+
+	if c.raw {
+		writeLines(&b, c.tail)
+	} else {
+		b.WriteString(c.newline)
+	}
+
+	p := b.Bytes()
+	if c.raw && !c.eol {
+		p = bytes.TrimSuffix(bytes.TrimSuffix(p, []byte("\n")), []byte("\r"))
 	}
+	_, err = buf.Write(p)
+	return err
+}
 
-	for _, section := range c.GetSectionList() {
-		options := c.GetSectionKeyList(section)
+// omitSection reports whether the section is not written: all its options
+// are omitted defaults, see Options.Defaults, or were included.
+func (c *Config) omitSection(section string) bool {
+	return c.options.Defaults == OmitDefaults && c.isDefaultedSection(section) || c.isIncludedSection(section)
+}
 
-		// Skip default section if empty.
-		if section == DEFAULT_SECTION && len(options) == 0 {
+// segments returns the parts of the sections which follow each part of the
+// source, see tValue.header.
+func (c *Config) segments() map[segment][]segment {
+	next := make(map[segment][]segment)
+	for _, section := range c.sections {
+		if c.omitSection(section) {
 			continue
 		}
+		for _, option := range c.optionListMap[section] {
+			if c.isSegment(section, option) {
+				tValue := c.dataMap[section][option]
+				next[tValue.after] = append(next[tValue.after], segment{section, option})
+			}
+		}
+	}
+	return next
+}
 
-		if _, err = buf.WriteString("\r\n[" + section + "]\r\n"); err != nil {
-			return err
+// isSegment reports whether the option starts a part of its section which
+// is written after the part of the source its header follows. The header of
+// an option which follows a removed part is written with the option, in the
+// part before it. An option named "" is never read, so it starts no part.
+func (c *Config) isSegment(section, option string) bool {
+	if option == "" {
+		return false
+	}
+	for option != "" {
+		tValue, ok := c.dataMap[section][option]
+		if !ok || tValue.header == nil {
+			return false
 		}
+		section, option = tValue.after.section, tValue.after.option
+	}
+	_, ok := c.dataMap[section]
+	return ok && !c.omitSection(section)
+}
 
-		for _, option := range options {
-			s := fmt.Sprint(option, c.separator, c.dataMap[section][option].v, "\r\n")
-			if _, err = buf.WriteString(s); err != nil {
-				return err
-			}
+// writeSegment writes the options of the part of the section, up to the
+// next part, then the parts which follow it.
+func (c *Config) writeSegment(b *bytes.Buffer, seg segment, next map[segment][]segment) {
+	options := c.optionListMap[seg.section]
+	i := 0
+	if seg.option != "" {
+		i = c.dataMap[seg.section][seg.option].position
+	} else {
+		writeLines(b, c.overwritten[seg])
+	}
+	for j, option := range options[i:] {
+		if (j > 0 || seg.option == "") && c.isSegment(seg.section, option) {
+			break
 		}
+		c.writeOption(b, seg.section, option)
 	}
 
-	if _, err = buf.WriteString("\r\n"); err != nil {
-		return err
+	for _, seg := range next[seg] {
+		c.writeSegment(b, seg, next)
 	}
-	return nil
+}
+
+// writeOption writes the option, with the source text before it.
+func (c *Config) writeOption(b *bytes.Buffer, section, option string) {
+	tValue := c.dataMap[section][option]
+	key := formatKey(option)
+	if tValue.auto {
+		key = "-"
+	}
+	writeLines(b, tValue.header)
+	writeLines(b, tValue.comments)
+
+	switch {
+	case tValue.included && tValue.lines == nil:
+	case tValue.included:
+		writeLines(b, tValue.lines)
+	case tValue.defaulted && c.options.Defaults == OmitDefaults:
+	case tValue.defaulted && c.options.Defaults == CommentDefaults:
+		value := strings.Replace(tValue.v, "\n", c.newline+c.comment+"\t", -1)
+		b.WriteString(c.comment + key + c.separator + value + c.newline)
+	case tValue.lines != nil && tValue.v == tValue.rawv:
+		writeLines(b, tValue.lines)
+	case tValue.lines != nil && tValue.rawv == "" && !strings.HasSuffix(tValue.prefix, `"""`):
+		// the prefix of an empty value has no spacing after the separator
+		spacing := c.separator[len(strings.TrimRight(c.separator, " ")):]
+		b.WriteString(tValue.prefix + spacing + c.formatValue(tValue.v) + tValue.suffix)
+	case tValue.lines != nil:
+		b.WriteString(tValue.prefix + c.formatValue(tValue.v) + tValue.suffix)
+	default:
+		b.WriteString(key + c.separator + c.formatValue(tValue.v) + c.newline)
+	}
+	if option != "" {
+		writeLines(b, c.overwritten[segment{section, option}])
+	}
+}
+
+// hasHeader reports whether the source lines of a section end with its
+// header line.
+func hasHeader(lines []string) bool {
+	return len(lines) > 0 && strings.HasPrefix(strings.TrimSpace(lines[len(lines)-1]), "[")
+}
+
+func writeLines(b *bytes.Buffer, lines []string) {
+	for _, line := range lines {
+		b.WriteString(line)
+	}
+}
+
+// formatKey quotes the option name if it could not be read back otherwise.
+func formatKey(option string) string {
+	if option != "" && !strings.ContainsAny(option, "=:") && !strings.ContainsAny(option[:1], "#;[ \t\"`") &&
+		strings.TrimSpace(option) == option {
+		return option
+	}
+	switch {
+	case strings.Contains(option, "\"") && strings.Contains(option, "`"):
+		return `"""` + option + `"""`
+	case strings.Contains(option, "\""):
+		return "`" + option + "`"
+	}
+	return `"` + option + `"`
+}
+
+// formatValue writes the lines of a multi-line value as continuation lines.
+func (c *Config) formatValue(value string) string {
+	return strings.Replace(value, "\n", c.newline+"\t", -1)
 }